@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"lateslip/sessions"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PUT /admin/users/:id/disable
+//
+// Blocks the account and ends its sessions straight away.
+func DisableUser(c *gin.Context) {
+	setUserDisabled(c, true)
+}
+
+// PUT /admin/users/:id/enable
+func EnableUser(c *gin.Context) {
+	setUserDisabled(c, false)
+}
+
+func setUserDisabled(c *gin.Context, disabled bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+	if disabled && id.Hex() == c.GetString("user_id") {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "You cannot disable your own account"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"disabled": true, "disabled_at": now, "updated_at": now}}
+	if !disabled {
+		update = bson.M{"$unset": bson.M{"disabled": "", "disabled_at": ""}, "$set": bson.M{"updated_at": now}}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"password": 0})
+	var user models.User
+	err = initialializers.DB.Collection("users").FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "User not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to update user"})
+		return
+	}
+
+	message := "User enabled"
+	if disabled {
+		if err := sessions.RevokeUser(ctx, user.ID.Hex()); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "User disabled but their sessions could not be ended"})
+			return
+		}
+		message = "User disabled"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"user": gin.H{
+			"id":         user.ID.Hex(),
+			"fullname":   user.Fullname,
+			"email":      user.Email,
+			"role":       user.Role,
+			"disabled":   user.Disabled,
+			"disabledAt": user.DisabledAt,
+		},
+	})
+}
+
+// POST /admin/users/:id/unlock
+//
+// Clears the failed login count of the account, lifting a lockout.
+func UnlockUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err = initialializers.DB.Collection("users").FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"password": 0})).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "User not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch user"})
+		return
+	}
+
+	status, err := initialializers.LoginGuard.Status(ctx, user.Email)
+	if err == nil {
+		err = initialializers.LoginGuard.Unlock(ctx, user.Email)
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"message":        "User unlocked",
+		"failedAttempts": status.Failures,
+		"wasLocked":      time.Now().Before(status.LockedUntil),
+	})
+}
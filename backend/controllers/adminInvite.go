@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"lateslip/initialializers"
+	"lateslip/mailtemplates"
+	"lateslip/models"
+	"lateslip/outbox"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// inviteLifetime is how long an admin invite link can be used
+const inviteLifetime = 72 * time.Hour
+
+// registerError is a registration refused for a reason the caller can fix
+type registerError struct {
+	status  int
+	message string
+}
+
+func (e *registerError) Error() string { return e.message }
+
+// isBootstrapToken reports whether token is ADMIN_BOOTSTRAP_TOKEN, which registers
+// the first admin of a fresh installation
+func isBootstrapToken(token string) bool {
+	bootstrap := os.Getenv("ADMIN_BOOTSTRAP_TOKEN")
+	return bootstrap != "" && subtle.ConstantTimeCompare([]byte(token), []byte(bootstrap)) == 1
+}
+
+// pendingInviteFilter matches invites that can still be used
+func pendingInviteFilter(now time.Time) bson.M {
+	return bson.M{
+		"used_at":    bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+}
+
+// claimAdminInvite marks the invite behind token as used by userID; called inside the
+// registration transaction so a failed registration gives the invite back
+func claimAdminInvite(ctx context.Context, token, email string, userID primitive.ObjectID) error {
+	now := time.Now()
+	inviteCollection := initialializers.DB.Collection("admin_invites")
+
+	filter := pendingInviteFilter(now)
+	filter["token_hash"] = hashToken(token)
+	var invite models.AdminInvite
+	if err := inviteCollection.FindOne(ctx, filter).Decode(&invite); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &registerError{http.StatusForbidden, "Invite is invalid or has expired"}
+		}
+		return err
+	}
+	if invite.Email != models.NormalizeEmail(email) {
+		return &registerError{http.StatusForbidden, "This invite was sent to a different email address"}
+	}
+
+	// guard against the same link being used twice at the same time
+	filter["_id"] = invite.ID
+	result, err := inviteCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"used_at": now, "used_by": userID.Hex()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &registerError{http.StatusForbidden, "Invite is invalid or has expired"}
+	}
+	return nil
+}
+
+// POST /admin/invites
+func CreateAdminInvite(c *gin.Context) {
+	type body struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	var b body
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	email := models.NormalizeEmail(b.Email)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"email": email}).Err()
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "User already exists"})
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Database error"})
+		return
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create invite"})
+		return
+	}
+
+	invitedBy, inviterName := deciderFor(ctx, c)
+	now := time.Now()
+	invite := models.AdminInvite{
+		ID:        primitive.NewObjectID(),
+		TokenHash: hashToken(token),
+		Email:     email,
+		InvitedBy: invitedBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(inviteLifetime),
+	}
+	if inviterName == "" {
+		inviterName = "An administrator"
+	}
+
+	messages, err := emailMessages(primitive.NilObjectID, mailtemplates.AdminInvite, []recipient{{Email: email}}, mailtemplates.Data{
+		Link:      initialializers.AppBaseURL + "/admin/register?invite=" + token,
+		ExpiresAt: invite.ExpiresAt.In(initialializers.CampusLocation),
+		InvitedBy: inviterName,
+	})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to render invite email"})
+		return
+	}
+
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		// only the newest link sent to an address works
+		inviteCollection := initialializers.DB.Collection("admin_invites")
+		filter := pendingInviteFilter(now)
+		filter["email"] = email
+		if _, err := inviteCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now}}); err != nil {
+			return err
+		}
+		if _, err := inviteCollection.InsertOne(ctx, invite); err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, messages...)
+	})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create invite"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Invite sent to " + email, "invite": invite})
+}
+
+// GET /admin/invites
+func GetAdminInvites(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(100)
+	cursor, err := initialializers.DB.Collection("admin_invites").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch invites"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	invites := []models.AdminInvite{}
+	if err := cursor.All(ctx, &invites); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch invites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "invites": invites})
+}
+
+// DELETE /admin/invites/:id
+func RevokeAdminInvite(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid invite ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := pendingInviteFilter(now)
+	filter["_id"] = id
+	result, err := initialializers.DB.Collection("admin_invites").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now}})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to revoke invite"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "No pending invite with this ID"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Invite revoked"})
+}
+
+// registerAdmin inserts the admin if token is a valid invite for their email, or the
+// bootstrap token while no admin exists yet
+func registerAdmin(ctx context.Context, user models.User, token string) error {
+	return initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		userCollection := initialializers.DB.Collection("users")
+		if isBootstrapToken(token) {
+			admins, err := userCollection.CountDocuments(ctx, bson.M{"role": "admin"})
+			if err != nil {
+				return err
+			}
+			if admins > 0 {
+				return &registerError{http.StatusForbidden, "An admin already exists, ask them for an invite"}
+			}
+		} else if err := claimAdminInvite(ctx, token, user.Email, user.ID); err != nil {
+			return err
+		}
+
+		err := userCollection.FindOne(ctx, bson.M{"email": user.Email}).Err()
+		if err == nil {
+			return &registerError{http.StatusConflict, "User already exists"}
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+		_, err = userCollection.InsertOne(ctx, user)
+		return err
+	})
+}
+
+// registerStatus maps a registration error to the response status and message. A
+// duplicate key is another registration of the same email that got in first.
+func registerStatus(err error) (int, string) {
+	var refused *registerError
+	if errors.As(err, &refused) {
+		return refused.status, refused.message
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return http.StatusConflict, "User already exists"
+	}
+	return http.StatusInternalServerError, "Error creating user"
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"lateslip/sessions"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionClient records where a session is used from
+func sessionClient(c *gin.Context) sessions.Client {
+	return sessions.Client{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+// tokenResponse is the body of a successful login or refresh. "token" is the access
+// token, sent as "Authorization: Bearer <token>".
+func tokenResponse(message string, tokens sessions.Tokens) gin.H {
+	return gin.H{
+		"success":      true,
+		"message":      message,
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+	}
+}
+
+type refreshBody struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// POST /auth/refresh
+//
+// Swaps a refresh token for a new access token and a new refresh token. Each refresh
+// token works once.
+func RefreshSession(c *gin.Context) {
+	var b refreshBody
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	tokens, err := sessions.Refresh(ctx, b.RefreshToken, sessionClient(c))
+	switch {
+	case errors.Is(err, sessions.ErrInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid or expired refresh token"})
+		return
+	case errors.Is(err, sessions.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "This account has been disabled"})
+		return
+	case err != nil:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse("Session refreshed", tokens))
+}
+
+// POST /auth/logout
+//
+// Ends the session the refresh token belongs to, including its access tokens.
+func Logout(c *gin.Context) {
+	var b refreshBody
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := sessions.Logout(ctx, b.RefreshToken); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Logged out"})
+}
+
+// POST /auth/logout-all
+//
+// Ends every session of the signed-in user, on all devices.
+func LogoutAll(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := sessions.RevokeUser(ctx, c.GetString("user_id")); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Logged out on all devices"})
+}
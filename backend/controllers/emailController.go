@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"lateslip/initialializers"
+	"lateslip/mailtemplates"
+	"lateslip/models"
+	"lateslip/outbox"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recipient is a resolved email address with a display name
+type recipient struct {
+	Name  string
+	Email string
+}
+
+// adminRecipients returns the addresses that are notified about new late slip requests.
+// ADMIN_NOTIFY_EMAILS (comma separated) takes precedence over the admin users in the database.
+func adminRecipients(ctx context.Context) ([]recipient, error) {
+	var recipients []recipient
+	if list := os.Getenv("ADMIN_NOTIFY_EMAILS"); strings.TrimSpace(list) != "" {
+		for _, email := range strings.Split(list, ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				recipients = append(recipients, recipient{Name: "Admin", Email: email})
+			}
+		}
+		return recipients, nil
+	}
+
+	cursor, err := initialializers.DB.Collection("users").Find(ctx, bson.M{"role": "admin"})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var admins []models.User
+	if err := cursor.All(ctx, &admins); err != nil {
+		return nil, err
+	}
+	for _, admin := range admins {
+		recipients = append(recipients, recipient{Name: admin.Fullname, Email: admin.Email})
+	}
+	return recipients, nil
+}
+
+// studentRecipient returns the address of the student who requested the late slip
+func studentRecipient(ctx context.Context, lateSlip models.LateSlip) []recipient {
+	var user models.User
+	err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"_id": lateSlip.StudentID}).Decode(&user)
+	if err == nil && user.Email != "" {
+		return []recipient{{Name: user.Fullname, Email: user.Email}}
+	}
+
+	var student models.Student
+	err = initialializers.DB.Collection("students").FindOne(ctx, bson.M{"_id": lateSlip.StudentRecordID}).Decode(&student)
+	if err == nil && student.Email != "" {
+		return []recipient{{Name: student.Name, Email: student.Email}}
+	}
+
+	log.Printf("No email address found for the student of late slip %s", lateSlip.ID.Hex())
+	return nil
+}
+
+// emailMessages renders the event's template for every recipient and wraps the results as outbox messages
+func emailMessages(lateSlipID primitive.ObjectID, event string, recipients []recipient, data mailtemplates.Data) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	for _, to := range recipients {
+		data.RecipientName = to.Name
+		email, err := mailtemplates.Render(event, data)
+		if err != nil {
+			return nil, err
+		}
+		message := outbox.NewMessage(lateSlipID, event, to.Name, to.Email, email.Subject, email.Text, email.HTML)
+		message.Secret = mailtemplates.Secret(event)
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// lateSlipEmailData fills the template fields shared by every late slip email
+func lateSlipEmailData(lateSlip models.LateSlip) mailtemplates.Data {
+	return mailtemplates.Data{
+		LateSlipID:  lateSlip.ID.Hex(),
+		StudentName: lateSlip.StudentName,
+		RosterID:    lateSlip.RosterID,
+		Level:       lateSlip.Level,
+		Semester:    lateSlip.Semester,
+		Reason:      lateSlip.Reason,
+		Status:      lateSlip.Status,
+		Time:        lateSlip.UpdatedAt,
+		ModuleCode:  lateSlip.ModuleCode,
+		ModuleName:  lateSlip.ModuleName,
+		RoomName:    lateSlip.RoomName,
+		Instructor:  lateSlip.InstructorName,
+	}
+}
+
+// GET /admin/lateslips/:id/deliveries
+func GetLateSlipDeliveries(c *gin.Context) {
+	lateSlipID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid late slip ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := initialializers.DB.Collection("email_deliveries").Find(
+		ctx,
+		bson.M{"late_slip_id": lateSlipID},
+		options.Find().SetSort(bson.M{"created_at": 1}),
+	)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email deliveries"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.EmailDelivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode email deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "deliveries": deliveries})
+}
@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"lateslip/initialializers"
+	"lateslip/mailtemplates"
+	"lateslip/models"
+	"lateslip/outbox"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// verificationLifetime is how long the link and code of a verification email work
+	verificationLifetime = 24 * time.Hour
+	// maxCodeAttempts codes can be entered per account, however many emails were sent;
+	// after that only the link verifies it
+	maxCodeAttempts = 10
+)
+
+var errVerificationInvalid = errors.New("verification link or code is invalid or has expired")
+
+func newVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// codeHash salts the six digit code with the verification ID
+func codeHash(id primitive.ObjectID, code string) string {
+	return hashToken(id.Hex() + ":" + code)
+}
+
+// sendEmailVerification replaces the user's pending verification with a new one and
+// queues the email; call it inside the transaction that creates the account
+func sendEmailVerification(ctx context.Context, user models.User) error {
+	token, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	code, err := newVerificationCode()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	verification := models.EmailVerification{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(verificationLifetime),
+	}
+	verification.CodeHash = codeHash(verification.ID, code)
+
+	messages, err := emailMessages(primitive.NilObjectID, mailtemplates.VerifyEmail, []recipient{{Name: user.Fullname, Email: user.Email}}, mailtemplates.Data{
+		Link:      initialializers.AppBaseURL + "/verify-email?token=" + token,
+		Code:      code,
+		ExpiresAt: verification.ExpiresAt.In(initialializers.CampusLocation),
+	})
+	if err != nil {
+		return err
+	}
+
+	verificationCollection := initialializers.DB.Collection("email_verifications")
+	_, err = verificationCollection.UpdateMany(ctx,
+		bson.M{"user_id": user.ID, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"expires_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := verificationCollection.InsertOne(ctx, verification); err != nil {
+		return err
+	}
+	return outbox.Enqueue(ctx, messages...)
+}
+
+// findVerification returns the pending verification matching the link token, or the
+// code entered for the account with this email. Every code uses up one of the account's
+// attempts.
+func findVerification(ctx context.Context, token, email, code string) (models.EmailVerification, error) {
+	var verification models.EmailVerification
+	verificationCollection := initialializers.DB.Collection("email_verifications")
+	pending := bson.M{
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	if token != "" {
+		pending["token_hash"] = hashToken(token)
+		err := verificationCollection.FindOne(ctx, pending).Decode(&verification)
+		if err == mongo.ErrNoDocuments {
+			return verification, errVerificationInvalid
+		}
+		return verification, err
+	}
+
+	// take the attempt before comparing, so concurrent guesses cannot exceed the budget
+	var user models.User
+	err := initialializers.DB.Collection("users").FindOneAndUpdate(ctx,
+		bson.M{"email": email, "pending_verification": true, "code_attempts": bson.M{"$not": bson.M{"$gte": maxCodeAttempts}}},
+		bson.M{"$inc": bson.M{"code_attempts": 1}},
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return verification, errVerificationInvalid
+	}
+	if err != nil {
+		return verification, err
+	}
+
+	pending["user_id"] = user.ID
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if err := verificationCollection.FindOne(ctx, pending, opts).Decode(&verification); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return verification, errVerificationInvalid
+		}
+		return verification, err
+	}
+	if subtle.ConstantTimeCompare([]byte(codeHash(verification.ID, code)), []byte(verification.CodeHash)) != 1 {
+		return verification, errVerificationInvalid
+	}
+	return verification, nil
+}
+
+// refuseThrottledVerification answers 429 while the address or the IP address has used
+// up its verification attempts for now, and reports whether it did
+func refuseThrottledVerification(c *gin.Context, email string) bool {
+	wait, _, err := initialializers.VerificationGuard.Check(c.Request.Context(), email, c.ClientIP(), time.Now())
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Database error"})
+		return true
+	}
+	if wait <= 0 {
+		return false
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "error": fmt.Sprintf("Too many attempts, try again in %d seconds", seconds), "retryAfter": seconds})
+	return true
+}
+
+// countVerificationAttempt charges a wrong code or a resent email to the address and
+// the IP address
+func countVerificationAttempt(c *gin.Context, email string) {
+	if err := initialializers.VerificationGuard.Fail(c.Request.Context(), email, c.ClientIP(), time.Now()); err != nil {
+		c.Error(err)
+	}
+}
+
+// POST /student/verify-email
+//
+// Takes either the token from the emailed link, or the email address and the code.
+func VerifyEmail(c *gin.Context) {
+	type body struct {
+		Token string `json:"token"`
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+	var b body
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	b.Email, b.Code = models.NormalizeEmail(b.Email), strings.TrimSpace(b.Code)
+	if b.Token == "" && (b.Email == "" || b.Code == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Send the token from the link, or your email and the code"})
+		return
+	}
+	if b.Token != "" {
+		// the link identifies the account, an email sent alongside it does not count
+		b.Email = ""
+	}
+	if refuseThrottledVerification(c, b.Email) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	verification, err := findVerification(ctx, b.Token, b.Email, b.Code)
+	if errors.Is(err, errVerificationInvalid) {
+		countVerificationAttempt(c, b.Email)
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Verification link or code is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to verify email"})
+		return
+	}
+
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		result, err := initialializers.DB.Collection("email_verifications").UpdateOne(ctx,
+			bson.M{"_id": verification.ID, "used_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"used_at": now}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errVerificationInvalid
+		}
+		_, err = initialializers.DB.Collection("users").UpdateOne(ctx,
+			bson.M{"_id": verification.UserID},
+			bson.M{"$unset": bson.M{"pending_verification": "", "code_attempts": ""}, "$set": bson.M{"email_verified_at": now, "updated_at": now}},
+		)
+		return err
+	})
+	if errors.Is(err, errVerificationInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Verification link or code is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to verify email"})
+		return
+	}
+
+	if b.Email != "" {
+		if err := initialializers.VerificationGuard.Succeed(c.Request.Context(), b.Email); err != nil {
+			c.Error(err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Email verified, you can now log in"})
+}
+
+// POST /student/verify-email/resend
+//
+// The response does not say whether the address has a pending account. Every request
+// counts against the address's verification attempts, whether or not it has one.
+func ResendEmailVerification(c *gin.Context) {
+	type body struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	var b body
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	response := gin.H{"success": true, "message": "If the address has an account waiting for verification, a new email has been sent"}
+	b.Email = models.NormalizeEmail(b.Email)
+	if refuseThrottledVerification(c, b.Email) {
+		return
+	}
+	countVerificationAttempt(c, b.Email)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"email": b.Email, "pending_verification": true}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Database error"})
+		return
+	}
+
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		return sendEmailVerification(ctx, user)
+	})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
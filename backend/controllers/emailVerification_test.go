@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"lateslip/initialializers"
+	"lateslip/loginguard"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestVerifyEmailCode(t *testing.T) {
+	mt := newMockDB(t)
+
+	userID := primitive.NewObjectID()
+	verificationID := primitive.NewObjectID()
+	user := bson.D{{Key: "_id", Value: userID}, {Key: "email", Value: "ada@college.edu"}, {Key: "pending_verification", Value: true}}
+	verification := bson.D{
+		{Key: "_id", Value: verificationID},
+		{Key: "user_id", Value: userID},
+		{Key: "code_hash", Value: codeHash(verificationID, "123456")},
+		{Key: "expires_at", Value: time.Now().Add(time.Hour)},
+	}
+	body := map[string]string{"email": " Ada@College.edu", "code": "654321"}
+	verify := func(mt *mtest.T, body map[string]string) (int, []string) {
+		w := serveJSON(mt.T, VerifyEmail, http.MethodPost, "/student/verify-email", "/student/verify-email", body)
+		return w.Code, startedCommands(mt)
+	}
+	accountFailures := func() int {
+		entry, _ := initialializers.VerificationGuard.Status(context.Background(), "ada@college.edu")
+		return entry.Failures
+	}
+
+	mt.Run("wrong code uses an attempt of the account", func(mt *mtest.T) {
+		useMockDB(mt)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: user}),
+			mtest.CreateCursorResponse(0, "test.email_verifications", mtest.FirstBatch, verification),
+		)
+		status, commands := verify(mt, body)
+		if status != http.StatusBadRequest || !reflect.DeepEqual(commands, []string{"findAndModify", "find"}) {
+			mt.Fatalf("VerifyEmail() = %d after %v", status, commands)
+		}
+		filter := mt.GetStartedEvent().Command.Lookup("query").Document()
+		if filter.Lookup("email").StringValue() != "ada@college.edu" || filter.Lookup("code_attempts").Document().Lookup("$not", "$gte").Int32() != maxCodeAttempts {
+			mt.Fatalf("attempt taken with filter %s", filter)
+		}
+		if failures := accountFailures(); failures != 1 {
+			mt.Fatalf("account failures = %d, want 1", failures)
+		}
+	})
+
+	mt.Run("used up budget is refused without comparing", func(mt *mtest.T) {
+		useMockDB(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+		status, commands := verify(mt, map[string]string{"email": "ada@college.edu", "code": "123456"})
+		if status != http.StatusBadRequest || !reflect.DeepEqual(commands, []string{"findAndModify"}) {
+			mt.Fatalf("VerifyEmail() = %d after %v", status, commands)
+		}
+	})
+
+	mt.Run("throttled account is refused before the database", func(mt *mtest.T) {
+		useMockDB(mt)
+		for i := 0; i < loginguard.VerificationPolicy.AccountLock; i++ {
+			initialializers.VerificationGuard.Fail(context.Background(), "ada@college.edu", "198.51.100.1", time.Now())
+		}
+		status, commands := verify(mt, body)
+		if status != http.StatusTooManyRequests || len(commands) != 0 {
+			mt.Fatalf("VerifyEmail() = %d after %v", status, commands)
+		}
+	})
+
+	mt.Run("right code verifies the account", func(mt *mtest.T) {
+		useMockDB(mt)
+		initialializers.VerificationGuard.Fail(context.Background(), "ada@college.edu", "198.51.100.1", time.Now())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: user}),
+			mtest.CreateCursorResponse(0, "test.email_verifications", mtest.FirstBatch, verification),
+			responseOK(1),
+			responseOK(1),
+			mtest.CreateSuccessResponse(),
+		)
+		status, commands := verify(mt, map[string]string{"email": "ada@college.edu", "code": "123456"})
+		if status != http.StatusOK {
+			mt.Fatalf("VerifyEmail() = %d after %v", status, commands)
+		}
+		if update := mt.GetAllStartedEvents()[3].Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$unset", "code_attempts"); update.IsZero() {
+			mt.Fatal("verifying does not clear the code attempts")
+		}
+		if failures := accountFailures(); failures != 0 {
+			mt.Fatalf("account failures = %d after verifying, want 0", failures)
+		}
+	})
+}
+
+func TestResendEmailVerificationIsThrottled(t *testing.T) {
+	mt := newMockDB(t)
+	mt.Run("unknown address", func(mt *mtest.T) {
+		useMockDB(mt)
+		for i := 0; i < loginguard.VerificationPolicy.FreeAttempts; i++ {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch))
+			w := serveJSON(mt.T, ResendEmailVerification, http.MethodPost, "/resend", "/resend", map[string]string{"email": "nobody@college.edu"})
+			assertStatus(mt.T, w, http.StatusOK)
+		}
+		w := serveJSON(mt.T, ResendEmailVerification, http.MethodPost, "/resend", "/resend", map[string]string{"email": "NOBODY@college.edu"})
+		assertStatus(mt.T, w, http.StatusTooManyRequests)
+		if w.Header().Get("Retry-After") == "" {
+			mt.Fatal("throttled resend has no Retry-After")
+		}
+	})
+}
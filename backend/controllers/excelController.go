@@ -2,282 +2,207 @@ package controllers
 
 import (
 	"context"
-	"lateslip/initialializers"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"lateslip/models"
-	"net/http"
+	"lateslip/spreadsheet"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
-	"github.com/xuri/excelize/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func UploadStudentData(c *gin.Context) {
-	//TODO: need to update the excel cells to match the model fields
-	// Right now, all the fields are assumed
+// maxUploadSize limits how much of an uploaded spreadsheet is read into memory
+const maxUploadSize = 20 << 20
+
+// upload is a spreadsheet read from the request
+type upload struct {
+	Data     []byte
+	FileName string
+	FileHash string // hex SHA-256 of Data
+}
 
+// readUpload reads the uploaded spreadsheet into memory and checks from its content that it
+// is an xlsx, ods or CSV file. On failure it writes the error response and returns false.
+func readUpload(c *gin.Context) (upload, bool) {
 	//get the file from the request
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
 		c.JSON(400, gin.H{"error": "File not found"})
-		return
+		return upload{}, false
 	}
-
-	//check if the file is an Excel file
-	if !strings.HasSuffix(fileHeader.Filename, ".xlsx") {
-		c.JSON(400, gin.H{"error": "Invalid file type. Please upload an Excel file."})
-		return
+	if fileHeader.Size > maxUploadSize {
+		c.JSON(400, gin.H{"error": "File is too large"})
+		return upload{}, false
 	}
 
 	// Open the uploaded file
 	file, err := fileHeader.Open()
 	if err != nil {
 		c.JSON(400, gin.H{"error": "Failed to open file"})
-		return
+		return upload{}, false
 	}
 	defer file.Close()
 
-	// Read the Excel file
-	xlsx, err := excelize.OpenReader(file)
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadSize))
 	if err != nil {
-		c.JSON(400, gin.H{
-			"success": false,
-			"error":   "Failed to parse Excel file",
-		})
-		return
+		c.JSON(400, gin.H{"error": "Failed to read file"})
+		return upload{}, false
 	}
-	defer xlsx.Close()
 
-	// Get all rows from Sheet1
-	rows, err := xlsx.GetRows("Sheet1")
-	if err != nil || len(rows) < 2 {
+	if _, err := spreadsheet.Detect(data); err != nil {
 		c.JSON(400, gin.H{
 			"success": false,
-			"error":   "Empty or invalid Excel sheet",
+			"error":   "Invalid file type. Please upload an Excel (.xlsx), OpenDocument (.ods) or CSV file: " + err.Error(),
 		})
-		return
+		return upload{}, false
 	}
+	sum := sha256.Sum256(data)
+	return upload{Data: data, FileName: fileHeader.Filename, FileHash: hex.EncodeToString(sum[:])}, true
+}
 
-	var newStudents []models.Student
-	validator := validator.New()
-
-	// First, get all existing students' emails in one query
-	studentsCollection := initialializers.DB.Collection("students")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Get all existing emails
-	cursor, err := studentsCollection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"email": 1}))
+// readSheet returns all rows of the requested sheet, or the active one
+func readSheet(data []byte, sheet string) ([][]string, error) {
+	rows, _, err := spreadsheet.Read(data, sheet)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "Failed to fetch existing students",
-		})
-		return
+		return nil, &jobError{message: "Failed to parse file: " + err.Error()}
 	}
-	defer cursor.Close(ctx)
-
-	existingEmails := make(map[string]primitive.ObjectID)
-	var existingStudent struct {
-		ID    primitive.ObjectID `bson:"_id"`
-		Email string             `bson:"email"`
-	}
-	for cursor.Next(ctx) {
-		if err := cursor.Decode(&existingStudent); err != nil {
-			continue
-		}
-		existingEmails[existingStudent.Email] = existingStudent.ID
+	if len(rows) < 2 {
+		return nil, &jobError{message: "Empty or invalid sheet"}
 	}
+	return rows, nil
+}
 
-	// Process Excel rows
-	for i, row := range rows[1:] { // Skip header row
-		if len(row) < 4 {
-			c.JSON(400, gin.H{
-				"success": false,
-				"error":   "Invalid data format in row " + strconv.Itoa(i+2),
-				"row":     row,
-			})
-			return
-		}
+// isDryRun reports whether the upload only asks for a preview
+func isDryRun(c *gin.Context) bool {
+	dryRun, _ := strconv.ParseBool(c.PostForm("dryRun"))
+	return dryRun
+}
 
-		//TODO: can use switch case for name, full name , email, email address
-		// 		ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-		// StudentID     string             `bson:"student_id" json:"student_id"`
-		// Name          string             `bson:"name" json:"name"`
-		// Email         string             `bson:"email" json:"email"`
-		// Semester      string             `bson:"semester" json:"semester"`
-		// Level         string             `bson:"level" json:"level"`
-		// LateSlipCount int                `bson:"late_slip_count" json:"late_slip_count"`
+func UploadStudentData(c *gin.Context) {
+	file, ok := readUpload(c)
+	if !ok {
+		return
+	}
 
-		student := models.Student{
-			StudentID:     row[0],
-			Name:          row[1],
-			Email:         row[2],
-			Semester:      row[3],
-			Level:         row[4],
-			LateSlipCount: 0, // Default value
-		}
+	sheet := c.PostForm("sheet")
+	dryRun := isDryRun(c)
+	deactivateMissing, _ := strconv.ParseBool(c.PostForm("deactivateMissing"))
 
-		// Validate the student struct
-		if err := validator.Struct(student); err != nil {
-			c.JSON(400, gin.H{
-				"success": false,
-				"error":   "Invalid data in row " + strconv.Itoa(i+2) + ": " + err.Error(),
-				"row":     row,
-			})
-			return
+	job := models.ImportJob{Kind: "students", DryRun: dryRun, FileName: file.FileName, FileHash: file.FileHash}
+	startImportJob(c, job, func(ctx context.Context, run *importJobRun) (gin.H, error) {
+		run.stage(ctx, "parsing", 0)
+		rows, err := readSheet(file.Data, sheet)
+		if err != nil {
+			return nil, err
 		}
 
-		// Check if email exists in our map
-		if _, exists := existingEmails[student.Email]; !exists {
-			student.ID = primitive.NewObjectID()
-			newStudents = append(newStudents, student)
+		// Work out which column holds which field from the header row
+		columns, parsed, err := parseStudentRows(rows)
+		if err != nil {
+			return nil, &jobError{message: err.Error(), result: gin.H{"columns": columns}}
 		}
-	}
 
-	// Bulk insert new students
-	if len(newStudents) > 0 {
-		var documents []interface{}
-		for _, student := range newStudents {
-			documents = append(documents, student)
+		run.stage(ctx, "checking", len(parsed))
+		if dryRun {
+			return studentPreview(ctx, run, rows[0], parsed, columns, deactivateMissing)
 		}
 
-		_, err = studentsCollection.InsertMany(ctx, documents)
+		// Insert new students and update changed ones, invalid rows are skipped and reported
+		run.stage(ctx, "importing", len(parsed))
+		history := newImportHistory(run)
+		stats, err := importStudents(ctx, parsed, columns, deactivateMissing, run.progress(ctx), history, nil)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"error":   "Failed to insert new students",
-			})
-			return
+			return nil, err
 		}
-	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Excel file processed successfully",
-		"stats": gin.H{
-			"new":      len(newStudents),
-			"existing": len(existingEmails),
-		},
+		response := gin.H{
+			"message":  "Excel file processed successfully",
+			"columns":  columns,
+			"stats":    stats,
+			"importId": history.ID.Hex(),
+		}
+		importErrorResponse(ctx, run, response, "students", rows[0], studentImportRows(parsed))
+		return response, nil
 	})
 }
 
 func UploadScheduleData(c *gin.Context) {
-	//get the file from the request
-	fileHeader, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(400, gin.H{"error": "File not found"})
-		return
-	}
-
-	//check if the file is an Excel file
-	if !strings.HasSuffix(fileHeader.Filename, ".xlsx") {
-		c.JSON(400, gin.H{"error": "Invalid file type. Please upload an Excel file."})
-		return
-	}
-
-	// Open the uploaded file
-	file, err := fileHeader.Open()
-	if err != nil {
-		c.JSON(400, gin.H{"error": "Failed to open file"})
-		return
-	}
-	defer file.Close()
-
-	// Read the Excel file
-	xlsx, err := excelize.OpenReader(file)
-	if err != nil {
-		c.JSON(400, gin.H{
-			"success": false,
-			"error":   "Failed to parse Excel file",
-		})
+	file, ok := readUpload(c)
+	if !ok {
 		return
 	}
-	defer xlsx.Close()
 
-	// Get all rows from Sheet1
-	rows, err := xlsx.GetRows("Sheet1")
-	if err != nil || len(rows) < 2 {
-		c.JSON(400, gin.H{
-			"success": false,
-			"error":   "Empty or invalid Excel sheet",
-		})
-		return
-	}
+	sheet := c.PostForm("sheet")
+	dryRun := isDryRun(c)
+	// replaceSemester=true makes the file the complete timetable of its semesters
+	replaceSemester, _ := strconv.ParseBool(c.PostForm("replaceSemester"))
 
-	var newSchedules []models.Schedule
-	validator := validator.New()
+	job := models.ImportJob{Kind: "schedules", DryRun: dryRun, FileName: file.FileName, FileHash: file.FileHash}
+	startImportJob(c, job, func(ctx context.Context, run *importJobRun) (gin.H, error) {
+		run.stage(ctx, "parsing", 0)
+		rows, err := readSheet(file.Data, sheet)
+		if err != nil {
+			return nil, err
+		}
 
-	// First, get all existing schedules' names in one query
-	schedulesCollection := initialializers.DB.Collection("schedules")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+		//process excel rows
+		parsed := parseScheduleRows(rows)
 
-	//process excel rows
-	for i, row := range rows[1:] { // Skip header row
-		if len(row) < 4 {
-			c.JSON(400, gin.H{
-				"success": false,
-				"error":   "Invalid data format in row " + strconv.Itoa(i+2),
-				"row":     row,
-			})
-			return
+		run.stage(ctx, "checking", len(parsed))
+		if dryRun {
+			return schedulePreview(ctx, run, rows[0], parsed, replaceSemester)
 		}
 
-		schedule := models.Schedule{
-			ID:             primitive.NewObjectID(),
-			ModuleCode:     row[0],
-			ModuleName:     row[1],
-			StartTime:      row[2],
-			EndTime:        row[3],
-			Day:            row[4],
-			RoomName:       row[5],
-			InstructorName: row[6],
-			Semester:       row[7],
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
+		// Import the valid rows, invalid rows are skipped and reported
+		run.stage(ctx, "importing", len(parsed))
+		history := newImportHistory(run)
+		stats, err := importSchedules(ctx, parsed, replaceSemester, run.progress(ctx), history, nil)
+		if err != nil {
+			return nil, err
 		}
 
-		// Validate the schedule struct
-		if err := validator.Struct(schedule); err != nil {
-			c.JSON(400, gin.H{
-				"success": false,
-				"error":   "Invalid data in row " + strconv.Itoa(i+2) + ": " + err.Error(),
-				"row":     row,
-			})
-			return
+		response := gin.H{
+			"message":  "Excel file processed successfully",
+			"warnings": scheduleOverlaps(validSchedules(parsed)),
+			"stats":    stats,
+			"importId": history.ID.Hex(),
 		}
-		newSchedules = append(newSchedules, schedule)
+		importErrorResponse(ctx, run, response, "schedules", rows[0], scheduleImportRows(parsed))
+		return response, nil
+	})
+}
 
-	}
-	if len(newSchedules) > 0 {
-		var documents []any
-		for _, schedule := range newSchedules {
-			documents = append(documents, schedule)
+func validSchedules(rows []scheduleRow) []models.Schedule {
+	var schedules []models.Schedule
+	for _, r := range rows {
+		if r.Action != rowInvalid {
+			schedules = append(schedules, r.Schedule)
 		}
+	}
+	return schedules
+}
 
-		_, err = schedulesCollection.InsertMany(ctx, documents)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"mesasge": "Failed to insert new schedules",
-				"error":   err.Error(),
-			})
-			return
+// scheduleOverlaps lists sessions of the same semester, or in the same room, whose times collide
+func scheduleOverlaps(schedules []models.Schedule) []string {
+	warnings := []string{}
+	for i := range schedules {
+		for j := i + 1; j < len(schedules); j++ {
+			a, b := schedules[i], schedules[j]
+			if !a.Overlaps(b) {
+				continue
+			}
+			switch {
+			case a.RoomName != "" && strings.EqualFold(a.RoomName, b.RoomName):
+				warnings = append(warnings, fmt.Sprintf("Room %s is double-booked on %s: %s %s-%s and %s %s-%s",
+					a.RoomName, a.Day, a.ModuleCode, a.StartTime, a.EndTime, b.ModuleCode, b.StartTime, b.EndTime))
+			case strings.EqualFold(a.Semester, b.Semester):
+				warnings = append(warnings, fmt.Sprintf("Semester %s has overlapping sessions on %s: %s %s-%s and %s %s-%s",
+					a.Semester, a.Day, a.ModuleCode, a.StartTime, a.EndTime, b.ModuleCode, b.StartTime, b.EndTime))
+			}
 		}
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Excel file processed successfully",
-		"stats": gin.H{
-			"new": len(newSchedules),
-		},
-	})
-
+	return warnings
 }
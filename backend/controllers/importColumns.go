@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// importColumn describes one field of an import file and the headers it may appear under
+type importColumn struct {
+	Field    string
+	Aliases  []string
+	Required bool
+}
+
+// studentColumns maps roster headers to Student fields. Extra aliases can be added with
+// STUDENT_IMPORT_ALIASES, e.g. "email=Mail|Student Email,name=Learner". It is built on
+// first use, after LoadEnvVariables has read .env.
+var studentColumns = sync.OnceValue(func() []importColumn {
+	return withEnvAliases("STUDENT_IMPORT_ALIASES", []importColumn{
+		{Field: "student_id", Aliases: []string{"student id", "studentid", "id", "roll no", "roll number", "college id"}, Required: true},
+		{Field: "name", Aliases: []string{"name", "full name", "fullname", "student name"}, Required: true},
+		{Field: "email", Aliases: []string{"email", "email address", "e-mail", "college email", "mail"}, Required: true},
+		{Field: "semester", Aliases: []string{"semester", "sem"}, Required: true},
+		{Field: "level", Aliases: []string{"level", "year", "year level"}},
+	})
+})
+
+// columnMapping is the result of matching a header row against a column list
+type columnMapping struct {
+	index   map[string]int
+	Mapped  map[string]string `json:"mapped"`            // field -> header it was read from
+	Missing []string          `json:"missing,omitempty"` // required fields with no matching header
+	Ignored []string          `json:"ignored,omitempty"` // headers that match no field
+}
+
+// mapHeader matches each header cell to a field by alias. Duplicate matches and
+// missing required columns are errors; unknown headers are only reported.
+func mapHeader(header []string, columns []importColumn) (columnMapping, error) {
+	mapping := columnMapping{index: map[string]int{}, Mapped: map[string]string{}}
+
+	for i, cell := range header {
+		name := normalizeHeader(cell)
+		if name == "" {
+			continue
+		}
+		field := ""
+		for _, column := range columns {
+			for _, alias := range column.Aliases {
+				if normalizeHeader(alias) == name {
+					field = column.Field
+				}
+			}
+		}
+		if field == "" {
+			mapping.Ignored = append(mapping.Ignored, strings.TrimSpace(cell))
+			continue
+		}
+		if previous, exists := mapping.index[field]; exists {
+			return mapping, fmt.Errorf("columns %q and %q both map to %s", header[previous], cell, field)
+		}
+		mapping.index[field] = i
+		mapping.Mapped[field] = strings.TrimSpace(cell)
+	}
+
+	for _, column := range columns {
+		if _, exists := mapping.index[column.Field]; column.Required && !exists {
+			mapping.Missing = append(mapping.Missing, column.Field)
+		}
+	}
+	if len(mapping.Missing) > 0 {
+		return mapping, fmt.Errorf("missing required columns: %s", strings.Join(mapping.Missing, ", "))
+	}
+	return mapping, nil
+}
+
+// Column returns the 0-based index of a field's column, or -1 when it is not mapped
+func (m columnMapping) Column(field string) int {
+	if i, exists := m.index[field]; exists {
+		return i
+	}
+	return -1
+}
+
+// Get returns the trimmed cell for a field, or "" when the column or cell is absent
+func (m columnMapping) Get(row []string, field string) string {
+	i, exists := m.index[field]
+	if !exists || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// normalizeHeader lower-cases a header and folds separators, so "Email_Address" matches "email address"
+func normalizeHeader(value string) string {
+	value = strings.ToLower(value)
+	value = strings.NewReplacer("_", " ", "-", " ", ".", " ").Replace(value)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func withEnvAliases(key string, columns []importColumn) []importColumn {
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		field, aliases, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		field = strings.TrimSpace(field)
+		found := false
+		for i := range columns {
+			if columns[i].Field == field {
+				columns[i].Aliases = append(columns[i].Aliases, strings.Split(aliases, "|")...)
+				found = true
+			}
+		}
+		if !found {
+			log.Printf("%s: unknown field %q", key, field)
+		}
+	}
+	return columns
+}
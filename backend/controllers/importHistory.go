@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rollbackError explains why an import cannot be rolled back
+type rollbackError struct{ message string }
+
+func (e *rollbackError) Error() string { return e.message }
+
+// newImportHistory starts the history entry of the import the job applies
+func newImportHistory(run *importJobRun) *models.ImportHistory {
+	return &models.ImportHistory{
+		JobID:      run.job.ID,
+		Kind:       run.job.Kind,
+		FileName:   run.job.FileName,
+		FileHash:   run.job.FileHash,
+		UploadedBy: run.job.CreatedBy,
+	}
+}
+
+// snapshotDocuments loads the current state of the documents an import is about to change
+func snapshotDocuments(ctx context.Context, collection *mongo.Collection, ids []primitive.ObjectID) (map[primitive.ObjectID]bson.M, error) {
+	snapshots := make(map[primitive.ObjectID]bson.M, len(ids))
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var document bson.M
+		if err := cursor.Decode(&document); err != nil {
+			return nil, err
+		}
+		if id, ok := document["_id"].(primitive.ObjectID); ok {
+			snapshots[id] = document
+		}
+	}
+	return snapshots, cursor.Err()
+}
+
+// updateFields lists the fields a $set/$unset update writes
+func updateFields(update bson.M) []string {
+	var fields []string
+	for _, operator := range []string{"$set", "$unset"} {
+		if values, ok := update[operator].(bson.M); ok {
+			for field := range values {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// saveImportHistory stores the history entry; called inside the import transaction
+func saveImportHistory(ctx context.Context, history *models.ImportHistory) error {
+	history.ID = primitive.NewObjectID()
+	history.CreatedAt = time.Now()
+	if history.Created == nil {
+		history.Created = []primitive.ObjectID{}
+	}
+	if history.Changed == nil {
+		history.Changed = []models.ImportSnapshot{}
+	}
+	if history.Removed == nil {
+		history.Removed = []models.ImportSnapshot{}
+	}
+	_, err := initialializers.DB.Collection("import_history").InsertOne(ctx, history)
+	return err
+}
+
+// GET /admin/imports/history
+func GetImportHistory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if kind := c.Query("kind"); kind != "" {
+		filter["kind"] = kind
+	}
+
+	// the snapshots can be large, the list only needs to say what was touched
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(100).
+		SetProjection(bson.M{"changed.before": 0, "removed.before": 0})
+	cursor, err := initialializers.DB.Collection("import_history").Find(ctx, filter, opts)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch import history"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	history := []models.ImportHistory{}
+	if err := cursor.All(ctx, &history); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch import history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "imports": history})
+}
+
+// POST /admin/imports/history/:id/rollback
+func RollbackImport(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid import ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	var history models.ImportHistory
+	var stats gin.H
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		historyCollection := initialializers.DB.Collection("import_history")
+		if err := historyCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&history); err != nil {
+			return err
+		}
+		if history.RolledBackAt != nil {
+			return &rollbackError{"This import has already been rolled back"}
+		}
+
+		// later imports of the same kind were planned against this one's result
+		newer, err := historyCollection.CountDocuments(ctx, bson.M{
+			"kind":           history.Kind,
+			"created_at":     bson.M{"$gt": history.CreatedAt},
+			"rolled_back_at": bson.M{"$exists": false},
+		})
+		if err != nil {
+			return err
+		}
+		if newer > 0 {
+			return &rollbackError{"A later import must be rolled back first"}
+		}
+
+		stats, err = rollbackImport(ctx, history)
+		if err != nil {
+			return err
+		}
+
+		result, err := historyCollection.UpdateOne(
+			ctx,
+			bson.M{"_id": id, "rolled_back_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"rolled_back_at": time.Now(), "rolled_back_by": c.GetString("user_id")}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return &rollbackError{"This import has already been rolled back"}
+		}
+		return nil
+	})
+	if err != nil {
+		var conflict *rollbackError
+		switch {
+		case err == mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Import not found"})
+		case errors.As(err, &conflict):
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": conflict.message})
+		case mongo.IsDuplicateKeyError(err):
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": "A removed record clashes with one added since the import"})
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to roll back import"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Import rolled back", "kind": history.Kind, "stats": stats})
+}
+
+// rollbackImport deletes what the import created, puts back the fields it changed and
+// re-inserts what it removed
+func rollbackImport(ctx context.Context, history models.ImportHistory) (gin.H, error) {
+	if history.Kind != "students" && history.Kind != "schedules" {
+		return nil, &rollbackError{"Unknown import kind " + history.Kind}
+	}
+	collection := initialializers.DB.Collection(history.Kind)
+
+	check := checkStudentRollback
+	if history.Kind == "schedules" {
+		check = checkScheduleRollback
+	}
+	if err := check(ctx, history); err != nil {
+		return nil, err
+	}
+
+	deleted := int64(0)
+	if len(history.Created) > 0 {
+		result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": history.Created}})
+		if err != nil {
+			return nil, err
+		}
+		deleted = result.DeletedCount
+	}
+
+	restored := 0
+	for _, snapshot := range history.Changed {
+		set, unset := bson.M{}, bson.M{}
+		for _, field := range snapshot.Fields {
+			if value, ok := snapshot.Before[field]; ok {
+				set[field] = value
+			} else {
+				unset[field] = ""
+			}
+		}
+		update := bson.M{}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		if len(update) == 0 {
+			continue
+		}
+		result, err := collection.UpdateOne(ctx, bson.M{"_id": snapshot.ID}, update)
+		if err != nil {
+			return nil, err
+		}
+		restored += int(result.MatchedCount)
+	}
+
+	reinserted := 0
+	for _, snapshot := range history.Removed {
+		if _, err := collection.InsertOne(ctx, snapshot.Before); err != nil {
+			return nil, err
+		}
+		reinserted++
+	}
+
+	return gin.H{"deleted": deleted, "restored": restored, "reinserted": reinserted}, nil
+}
+
+// checkStudentRollback refuses to undo a roster import that later activity depends on:
+// students it added who have registered or requested late slips, and students whose
+// late slip count it reset and who have requested or had slips approved since
+func checkStudentRollback(ctx context.Context, history models.ImportHistory) error {
+	if len(history.Created) > 0 {
+		used, err := initialializers.DB.Collection("lateslips").CountDocuments(ctx, bson.M{"student_record_id": bson.M{"$in": history.Created}})
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return &rollbackError{"Students added by this import have already requested late slips"}
+		}
+		registered, err := initialializers.DB.Collection("users").CountDocuments(ctx, bson.M{"student_id": bson.M{"$in": history.Created}})
+		if err != nil {
+			return err
+		}
+		if registered > 0 {
+			return &rollbackError{"Students added by this import have already registered an account"}
+		}
+	}
+
+	var reset []primitive.ObjectID
+	for _, snapshot := range history.Changed {
+		for _, field := range snapshot.Fields {
+			if field == "late_slip_count" {
+				reset = append(reset, snapshot.ID)
+			}
+		}
+	}
+	if len(reset) > 0 {
+		counted, err := initialializers.DB.Collection("students").CountDocuments(ctx, bson.M{
+			"_id": bson.M{"$in": reset},
+			"$or": []bson.M{{"late_slip_count": bson.M{"$ne": 0}}, {"pending_slip_count": bson.M{"$gt": 0}}},
+		})
+		if err != nil {
+			return err
+		}
+		if counted > 0 {
+			return &rollbackError{"Students moved to a new semester by this import have requested late slips since"}
+		}
+	}
+	return nil
+}
+
+// checkScheduleRollback refuses to undo a timetable import while late slips point at a
+// session it added, which would be deleted, or one it removed, which would come back
+func checkScheduleRollback(ctx context.Context, history models.ImportHistory) error {
+	sessions := append([]primitive.ObjectID{}, history.Created...)
+	for _, snapshot := range history.Removed {
+		sessions = append(sessions, snapshot.ID)
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+	used, err := initialializers.DB.Collection("lateslips").CountDocuments(ctx, bson.M{"schedule_id": bson.M{"$in": sessions}})
+	if err != nil {
+		return err
+	}
+	if used > 0 {
+		return &rollbackError{"Late slips refer to sessions this import added or removed"}
+	}
+	return nil
+}
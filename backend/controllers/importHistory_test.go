@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// countResponse answers the aggregate CountDocuments sends
+func countResponse(ns string, n int) bson.D {
+	return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+}
+
+func TestRollbackImport(t *testing.T) {
+	mt := newMockDB(t)
+
+	historyID := primitive.NewObjectID()
+	created, removed := primitive.NewObjectID(), primitive.NewObjectID()
+	history := func(kind string, changed ...bson.D) bson.D {
+		return mtest.CreateCursorResponse(0, "test.import_history", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: historyID},
+			{Key: "kind", Value: kind},
+			{Key: "created_at", Value: time.Now().Add(-time.Hour)},
+			{Key: "created", Value: bson.A{created}},
+			{Key: "changed", Value: changed},
+			{Key: "removed", Value: bson.A{bson.D{
+				{Key: "id", Value: removed},
+				{Key: "before", Value: bson.D{{Key: "_id", Value: removed}, {Key: "module_code", Value: "CS101"}}},
+			}}},
+		})
+	}
+	rollback := func(mt *mtest.T) (int, []string) {
+		route := "/admin/imports/history/:id/rollback"
+		w := serveJSON(mt.T, RollbackImport, http.MethodPost, route, "/admin/imports/history/"+historyID.Hex()+"/rollback", nil)
+		return w.Code, startedCommands(mt)
+	}
+
+	mt.Run("late slips use an added or removed session", func(mt *mtest.T) {
+		useMockDB(mt)
+		mt.AddMockResponses(
+			history("schedules"),
+			countResponse("test.import_history", 0),
+			countResponse("test.lateslips", 1),
+			mtest.CreateSuccessResponse(),
+		)
+		status, commands := rollback(mt)
+		if status != http.StatusConflict || !reflect.DeepEqual(commands, []string{"find", "aggregate", "aggregate", "abortTransaction"}) {
+			mt.Fatalf("RollbackImport() = %d after %v", status, commands)
+		}
+		match := mt.GetAllStartedEvents()[2].Command.Lookup("pipeline").Array().Index(0).Value().Document().Lookup("$match", "schedule_id", "$in")
+		var sessions []primitive.ObjectID
+		if err := match.Unmarshal(&sessions); err != nil || !reflect.DeepEqual(sessions, []primitive.ObjectID{created, removed}) {
+			mt.Fatalf("late slips counted for sessions %v, want %v", sessions, []primitive.ObjectID{created, removed})
+		}
+	})
+
+	mt.Run("sessions nobody refers to", func(mt *mtest.T) {
+		useMockDB(mt)
+		mt.AddMockResponses(
+			history("schedules"),
+			countResponse("test.import_history", 0),
+			countResponse("test.lateslips", 0),
+			responseOK(1), // delete the added session
+			responseOK(1), // put back the removed one
+			responseOK(1), // mark the import rolled back
+			mtest.CreateSuccessResponse(),
+		)
+		status, commands := rollback(mt)
+		want := []string{"find", "aggregate", "aggregate", "delete", "insert", "update", "commitTransaction"}
+		if status != http.StatusOK || !reflect.DeepEqual(commands, want) {
+			mt.Fatalf("RollbackImport() = %d after %v", status, commands)
+		}
+	})
+
+	mt.Run("students moved semester have pending requests", func(mt *mtest.T) {
+		useMockDB(mt)
+		reset := bson.D{
+			{Key: "id", Value: primitive.NewObjectID()},
+			{Key: "fields", Value: bson.A{"semester", "late_slip_count", "pending_slip_count"}},
+		}
+		mt.AddMockResponses(
+			history("students", reset),
+			countResponse("test.import_history", 0),
+			countResponse("test.lateslips", 0),
+			countResponse("test.users", 0),
+			countResponse("test.students", 1),
+			mtest.CreateSuccessResponse(),
+		)
+		if status, commands := rollback(mt); status != http.StatusConflict {
+			mt.Fatalf("RollbackImport() = %d after %v", status, commands)
+		}
+		match := mt.GetAllStartedEvents()[4].Command.Lookup("pipeline").Array().Index(0).Value().Document().Lookup("$match")
+		if pending := match.Document().Lookup("$or").Array().Index(1).Value().Document().Lookup("pending_slip_count"); pending.IsZero() {
+			mt.Fatalf("students counted with %s, want pending requests to count", match)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lateslip/events"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Import job statuses
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// importJobTimeout bounds how long a background import may keep the database busy,
+// reading and checking the file included
+const importJobTimeout = 10 * time.Minute
+
+// importTransactionTimeout caps the write stage of an import. All writes of one import
+// are a single MongoDB transaction, which the server aborts once it is older than
+// transactionLifetimeLimitSeconds (60 by default), so a file that cannot be written in
+// that time has to be split.
+const importTransactionTimeout = 60 * time.Second
+
+// importBatchSize is how many writes are sent per BulkWrite, and so how often progress is reported
+const importBatchSize = 500
+
+// progressFunc reports how many rows of the file have been handled
+type progressFunc func(processed int)
+
+// importJobRun is a running job; it saves every change and streams it to the uploader
+type importJobRun struct {
+	job models.ImportJob
+}
+
+// jobError is a failure whose message and partial result are shown to the uploader as is
+type jobError struct {
+	message string
+	result  gin.H
+}
+
+func (e *jobError) Error() string { return e.message }
+
+// importTransaction runs the writes of an import in one transaction, failing with a
+// message the uploader can act on when they take longer than the server allows
+func importTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, importTransactionTimeout)
+	defer cancel()
+
+	err := initialializers.WithTransaction(ctx, fn)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &jobError{message: fmt.Sprintf("Import did not finish within %v, the longest a database transaction may run; split the file and import the parts separately", importTransactionTimeout)}
+	}
+	return err
+}
+
+// startImportJob records a queued job, answers 202 with its ID and runs fn in the background.
+// fn returns the body the upload endpoint would have answered with.
+func startImportJob(c *gin.Context, job models.ImportJob, fn func(ctx context.Context, run *importJobRun) (gin.H, error)) {
+	now := time.Now()
+	job.ID = primitive.NewObjectID()
+	job.Status = jobQueued
+	job.CreatedBy = c.GetString("user_id")
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	if _, err := initialializers.DB.Collection("import_jobs").InsertOne(ctx, job); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to start import"})
+		return
+	}
+
+	run := &importJobRun{job: job}
+	go run.execute(fn)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":   true,
+		"message":   "Import started",
+		"jobId":     job.ID.Hex(),
+		"status":    job.Status,
+		"statusUrl": "/admin/imports/" + job.ID.Hex(),
+	})
+}
+
+func (r *importJobRun) execute(fn func(ctx context.Context, run *importJobRun) (gin.H, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), importJobTimeout)
+	defer cancel()
+
+	started := time.Now()
+	r.update(ctx, bson.M{"status": jobRunning, "started_at": started}, func(job *models.ImportJob) {
+		job.Status = jobRunning
+		job.StartedAt = &started
+	})
+
+	result, err := func() (result gin.H, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("import panicked: %v", p)
+			}
+		}()
+		return fn(ctx, r)
+	}()
+
+	finished := time.Now()
+	status, message, invalid := jobSucceeded, "", 0
+	if err != nil {
+		status, message = jobFailed, "Import failed"
+		if jobErr, ok := err.(*jobError); ok {
+			message, result = jobErr.message, jobErr.result
+		} else {
+			log.Printf("Import job %s failed: %v", r.job.ID.Hex(), err)
+		}
+	}
+	set := bson.M{"status": status, "stage": "done", "finished_at": finished}
+	if message != "" {
+		set["error"] = message
+	}
+	if result != nil {
+		if n, ok := result["invalid"].(int); ok {
+			invalid = n
+			set["invalid"] = n
+		}
+		if document, err := jsonDocument(result); err != nil {
+			log.Printf("Failed to store result of import job %s: %v", r.job.ID.Hex(), err)
+		} else {
+			set["result"] = document
+		}
+	}
+
+	// the job context may have run out, the final state must still be saved
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+	r.update(saveCtx, set, func(job *models.ImportJob) {
+		job.Status = status
+		job.Stage = "done"
+		job.Error = message
+		job.Invalid = invalid
+		job.FinishedAt = &finished
+	})
+}
+
+// stage moves the job to the next step of processing
+func (r *importJobRun) stage(ctx context.Context, stage string, total int) {
+	r.update(ctx, bson.M{"stage": stage, "total": total}, func(job *models.ImportJob) {
+		job.Stage = stage
+		job.Total = total
+	})
+}
+
+// progress is the progressFunc of this job
+func (r *importJobRun) progress(ctx context.Context) progressFunc {
+	return func(processed int) {
+		r.update(ctx, bson.M{"processed": processed}, func(job *models.ImportJob) {
+			job.Processed = processed
+		})
+	}
+}
+
+// update saves the changed fields and sends the new state to the uploader.
+// Progress is best effort, a failed save is only logged.
+func (r *importJobRun) update(ctx context.Context, set bson.M, apply func(job *models.ImportJob)) {
+	now := time.Now()
+	apply(&r.job)
+	r.job.UpdatedAt = now
+	set["updated_at"] = now
+
+	_, err := initialializers.DB.Collection("import_jobs").UpdateOne(ctx, bson.M{"_id": r.job.ID}, bson.M{"$set": set})
+	if err != nil {
+		log.Printf("Failed to update import job %s: %v", r.job.ID.Hex(), err)
+	}
+	events.NotifyImportJob(r.job)
+}
+
+// jsonDocument stores a response body the way it is sent to clients, which keeps the
+// fields of embedded row types that BSON would not inline
+func jsonDocument(body gin.H) (bson.M, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var document bson.M
+	err = json.Unmarshal(data, &document)
+	return document, err
+}
+
+// GET /admin/imports/:id
+func GetImportJob(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid import job ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var job models.ImportJob
+	err = initialializers.DB.Collection("import_jobs").FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Import job not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch import job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "job": job})
+}
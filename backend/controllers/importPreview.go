@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// previewLifetime is how long a dry-run result can be committed
+const previewLifetime = time.Hour
+
+func newPreviewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// savePreview stores the preview and fills in its token and expiry
+func savePreview(ctx context.Context, run *importJobRun, preview *models.ImportPreview) error {
+	token, err := newPreviewToken()
+	if err != nil {
+		return err
+	}
+	preview.Token = token
+	preview.FileName = run.job.FileName
+	preview.FileHash = run.job.FileHash
+	preview.CreatedBy = run.job.CreatedBy
+	preview.CreatedAt = time.Now()
+	preview.ExpiresAt = preview.CreatedAt.Add(previewLifetime)
+
+	_, err = initialializers.DB.Collection("import_previews").InsertOne(ctx, preview)
+	return err
+}
+
+// studentPreview stores the valid roster rows and returns the per-row diff,
+// including the students a deactivateMissing import would mark inactive
+func studentPreview(ctx context.Context, run *importJobRun, header []string, rows []studentRow, columns columnMapping, deactivateMissing bool) (gin.H, error) {
+	missing, err := planStudentImport(ctx, rows, columns, deactivateMissing)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary importSummary
+	preview := models.ImportPreview{Kind: "students", DeactivateMissing: deactivateMissing, Removed: studentIDs(missing)}
+	for _, r := range rows {
+		summary.add(r.Action)
+		if r.Action != rowInvalid {
+			preview.Students = append(preview.Students, r.Student)
+			preview.Rows = append(preview.Rows, previewRow(r.importRow))
+		}
+	}
+	summary.Removed = len(missing)
+
+	if err := savePreview(ctx, run, &preview); err != nil {
+		return nil, err
+	}
+
+	if missing == nil {
+		missing = []models.Student{}
+	}
+	response := gin.H{
+		"dryRun":      true,
+		"token":       preview.Token,
+		"expiresAt":   preview.ExpiresAt,
+		"columns":     columns,
+		"summary":     summary,
+		"rows":        rows,
+		"deactivated": missing,
+	}
+	importErrorResponse(ctx, run, response, "students", header, studentImportRows(rows))
+	return response, nil
+}
+
+// schedulePreview stores the valid timetable rows and returns the per-row diff,
+// including the sessions a replaceSemester import would remove
+func schedulePreview(ctx context.Context, run *importJobRun, header []string, rows []scheduleRow, replaceSemester bool) (gin.H, error) {
+	removed, err := planScheduleImport(ctx, rows, replaceSemester)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary importSummary
+	preview := models.ImportPreview{Kind: "schedules", ReplaceSemester: replaceSemester, Removed: scheduleIDs(removed)}
+	for _, r := range rows {
+		summary.add(r.Action)
+		if r.Action != rowInvalid {
+			preview.Schedules = append(preview.Schedules, r.Schedule)
+			preview.Rows = append(preview.Rows, previewRow(r.importRow))
+		}
+	}
+	summary.Removed = len(removed)
+
+	if err := savePreview(ctx, run, &preview); err != nil {
+		return nil, err
+	}
+
+	if removed == nil {
+		removed = []models.Schedule{}
+	}
+	response := gin.H{
+		"dryRun":    true,
+		"token":     preview.Token,
+		"expiresAt": preview.ExpiresAt,
+		"summary":   summary,
+		"rows":      rows,
+		"removed":   removed,
+		"warnings":  scheduleOverlaps(preview.Schedules),
+	}
+	importErrorResponse(ctx, run, response, "schedules", header, scheduleImportRows(rows))
+	return response, nil
+}
+
+func previewRow(r importRow) models.ImportPreviewRow {
+	return models.ImportPreviewRow{Row: r.Row, Action: r.Action, Changes: r.Changes}
+}
+
+// planCheck sees the rows an import is about to write and the IDs it would deactivate
+// or remove; an error stops the import
+type planCheck func(rows []importRow, removed []primitive.ObjectID) error
+
+var errPreviewOutdated = &jobError{message: "The data has changed since the preview was made; upload the file again to see what the import would do now"}
+
+// checkPreviewPlan refuses a plan that differs from what the preview showed
+func checkPreviewPlan(preview models.ImportPreview, rows []importRow, removed []primitive.ObjectID) error {
+	if len(rows) != len(preview.Rows) || len(removed) != len(preview.Removed) {
+		return errPreviewOutdated
+	}
+	for i, r := range rows {
+		if r.Action != preview.Rows[i].Action || !slices.Equal(r.Changes, preview.Rows[i].Changes) {
+			return errPreviewOutdated
+		}
+	}
+	shown := make(map[primitive.ObjectID]bool, len(preview.Removed))
+	for _, id := range preview.Removed {
+		shown[id] = true
+	}
+	for _, id := range removed {
+		if !shown[id] {
+			return errPreviewOutdated
+		}
+	}
+	return nil
+}
+
+// previewStudentRows turns the previewed students back into rows, numbered as in the sheet
+func previewStudentRows(preview models.ImportPreview) []studentRow {
+	rows := make([]studentRow, len(preview.Students))
+	for i, student := range preview.Students {
+		rows[i] = studentRow{importRow: importRow{Row: previewRowNumber(preview, i)}, Student: student}
+	}
+	return rows
+}
+
+// previewScheduleRows turns the previewed sessions back into rows, numbered as in the sheet
+func previewScheduleRows(preview models.ImportPreview) []scheduleRow {
+	rows := make([]scheduleRow, len(preview.Schedules))
+	for i, schedule := range preview.Schedules {
+		rows[i] = scheduleRow{importRow: importRow{Row: previewRowNumber(preview, i)}, Schedule: schedule}
+	}
+	return rows
+}
+
+func previewRowNumber(preview models.ImportPreview, i int) int {
+	if i < len(preview.Rows) {
+		return preview.Rows[i].Row
+	}
+	return i + 2
+}
+
+// planPreview plans the previewed records against the database as it is now and
+// compares the result with what the preview showed
+func planPreview(ctx context.Context, preview models.ImportPreview) error {
+	switch preview.Kind {
+	case "students":
+		rows := previewStudentRows(preview)
+		missing, err := planStudentImport(ctx, rows, columnMapping{}, preview.DeactivateMissing)
+		if err != nil {
+			return err
+		}
+		return checkPreviewPlan(preview, studentImportRows(rows), studentIDs(missing))
+	case "schedules":
+		rows := previewScheduleRows(preview)
+		removed, err := planScheduleImport(ctx, rows, preview.ReplaceSemester)
+		if err != nil {
+			return err
+		}
+		return checkPreviewPlan(preview, scheduleImportRows(rows), scheduleIDs(removed))
+	}
+	return nil
+}
+
+// POST /admin/imports/previews/:token/commit
+//
+// Refused with 409 when the database has changed since the preview in a way that makes
+// the import do something other than what the preview showed.
+func CommitImportPreview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	// take the preview so it can only be committed once
+	previews := initialializers.DB.Collection("import_previews")
+	var preview models.ImportPreview
+	err := previews.FindOneAndDelete(ctx, bson.M{"token": c.Param("token"), "expires_at": bson.M{"$gt": time.Now()}}).Decode(&preview)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Preview not found or expired"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch import preview"})
+		return
+	}
+
+	if err := planPreview(ctx, preview); err != nil {
+		if errors.Is(err, errPreviewOutdated) {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": errPreviewOutdated.message})
+			return
+		}
+		// put the preview back so the admin can retry
+		if _, restoreErr := previews.InsertOne(context.Background(), preview); restoreErr != nil {
+			log.Printf("Failed to restore import preview %s: %v", preview.Token, restoreErr)
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to check import preview"})
+		return
+	}
+	// the job plans again inside its transaction and stops if anything changed meanwhile
+	check := func(rows []importRow, removed []primitive.ObjectID) error {
+		return checkPreviewPlan(preview, rows, removed)
+	}
+
+	job := models.ImportJob{Kind: preview.Kind, FileName: preview.FileName, FileHash: preview.FileHash}
+	startImportJob(c, job, func(ctx context.Context, run *importJobRun) (gin.H, error) {
+		// the history names whoever uploaded the file, the job whoever committed it
+		history := newImportHistory(run)
+		history.UploadedBy = preview.CreatedBy
+
+		var stats any
+		var err error
+		switch preview.Kind {
+		case "students":
+			rows := previewStudentRows(preview)
+			run.stage(ctx, "importing", len(rows))
+			stats, err = importStudents(ctx, rows, columnMapping{}, preview.DeactivateMissing, run.progress(ctx), history, check)
+		case "schedules":
+			rows := previewScheduleRows(preview)
+			run.stage(ctx, "importing", len(rows))
+			stats, err = importSchedules(ctx, rows, preview.ReplaceSemester, run.progress(ctx), history, check)
+		}
+		if errors.Is(err, errPreviewOutdated) {
+			return nil, err
+		}
+		if err != nil {
+			// put the preview back so the admin can retry
+			if _, restoreErr := previews.InsertOne(context.Background(), preview); restoreErr != nil {
+				log.Printf("Failed to restore import preview %s: %v", preview.Token, restoreErr)
+			}
+			return nil, err
+		}
+		return gin.H{"message": "Import committed successfully", "previewToken": preview.Token, "stats": stats, "importId": history.ID.Hex()}, nil
+	})
+}
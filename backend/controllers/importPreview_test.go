@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"lateslip/models"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCommitImportPreview(t *testing.T) {
+	mt := newMockDB(t)
+
+	// the preview showed one new student
+	preview := mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "token", Value: "abc"},
+		{Key: "kind", Value: "students"},
+		{Key: "students", Value: bson.A{bson.D{
+			{Key: "student_id", Value: "S1"},
+			{Key: "name", Value: "Ada"},
+			{Key: "email", Value: "ada@example.com"},
+			{Key: "semester", Value: "2026-1"},
+		}}},
+		{Key: "rows", Value: bson.A{bson.D{{Key: "row", Value: 5}, {Key: "action", Value: rowNew}}}},
+		{Key: "expires_at", Value: time.Now().Add(time.Hour)},
+	}})
+	commit := func(mt *mtest.T) (int, []string) {
+		w := serveJSON(mt.T, CommitImportPreview, http.MethodPost, "/admin/imports/previews/:token/commit", "/admin/imports/previews/abc/commit", nil)
+		return w.Code, startedCommands(mt)
+	}
+
+	mt.Run("student was added since the preview", func(mt *mtest.T) {
+		useMockDB(mt)
+		mt.AddMockResponses(
+			preview,
+			mtest.CreateCursorResponse(0, "test.students", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "student_id", Value: "S1"},
+				{Key: "name", Value: "Ada"},
+				{Key: "email", Value: "ada@example.com"},
+				{Key: "semester", Value: "2026-1"},
+			}),
+		)
+		status, commands := commit(mt)
+		if status != http.StatusConflict || !reflect.DeepEqual(commands, []string{"findAndModify", "find"}) {
+			mt.Fatalf("CommitImportPreview() = %d after %v", status, commands)
+		}
+	})
+
+	mt.Run("re-plan fails", func(mt *mtest.T) {
+		useMockDB(mt)
+		mt.AddMockResponses(
+			preview,
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}),
+			responseOK(1),
+		)
+		status, commands := commit(mt)
+		if status != http.StatusInternalServerError || !reflect.DeepEqual(commands, []string{"findAndModify", "find", "insert"}) {
+			mt.Fatalf("CommitImportPreview() = %d after %v, want the preview put back", status, commands)
+		}
+	})
+}
+
+func TestCheckPreviewPlan(t *testing.T) {
+	removed := primitive.NewObjectID()
+	preview := models.ImportPreview{
+		Rows: []models.ImportPreviewRow{
+			{Row: 2, Action: rowNew},
+			{Row: 4, Action: rowChanged, Changes: []string{"name"}},
+		},
+		Removed: []primitive.ObjectID{removed},
+	}
+	planned := func(second importRow, removed ...primitive.ObjectID) error {
+		return checkPreviewPlan(preview, []importRow{{Row: 2, Action: rowNew}, second}, removed)
+	}
+
+	if err := planned(importRow{Row: 4, Action: rowChanged, Changes: []string{"name"}}, removed); err != nil {
+		t.Fatalf("same plan refused: %v", err)
+	}
+	outdated := map[string]error{
+		"action":          planned(importRow{Row: 4, Action: rowUnchanged}, removed),
+		"changes":         planned(importRow{Row: 4, Action: rowChanged, Changes: []string{"name", "level"}}, removed),
+		"removed":         planned(importRow{Row: 4, Action: rowChanged, Changes: []string{"name"}}, primitive.NewObjectID()),
+		"nothing removed": planned(importRow{Row: 4, Action: rowChanged, Changes: []string{"name"}}),
+	}
+	for name, err := range outdated {
+		if err != errPreviewOutdated {
+			t.Errorf("%s differs: err = %v, want errPreviewOutdated", name, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// reportLifetime is how long an error report can be downloaded
+const reportLifetime = 24 * time.Hour
+
+// saveImportReport stores the sheet with its row errors when any row is invalid and
+// returns the token to download it with, or "" when every row was fine
+func saveImportReport(ctx context.Context, run *importJobRun, kind string, header []string, rows []importRow) (string, error) {
+	report := models.ImportReport{Kind: kind, Header: header}
+	invalid := 0
+	for _, r := range rows {
+		if r.Action == rowInvalid {
+			invalid++
+		}
+		report.Rows = append(report.Rows, models.ImportReportRow{
+			Row:        r.Row,
+			Cells:      r.Cells,
+			Errors:     r.Errors,
+			BadColumns: r.BadColumns,
+		})
+	}
+	if invalid == 0 {
+		return "", nil
+	}
+
+	token, err := newPreviewToken()
+	if err != nil {
+		return "", err
+	}
+	report.Token = token
+	report.FileName = run.job.FileName
+	report.CreatedBy = run.job.CreatedBy
+	report.CreatedAt = time.Now()
+	report.ExpiresAt = report.CreatedAt.Add(reportLifetime)
+
+	_, err = initialializers.DB.Collection("import_reports").InsertOne(ctx, report)
+	return token, err
+}
+
+// importErrorResponse adds the row errors and the report download link to an upload response
+func importErrorResponse(ctx context.Context, run *importJobRun, response gin.H, kind string, header []string, rows []importRow) {
+	errors := invalidRows(rows)
+	response["invalid"] = len(errors)
+	response["errors"] = errors
+
+	token, err := saveImportReport(ctx, run, kind, header, rows)
+	if err != nil {
+		log.Printf("Failed to save import report for job %s: %v", run.job.ID.Hex(), err)
+		return
+	}
+	if token != "" {
+		response["reportToken"] = token
+		response["reportUrl"] = "/admin/imports/reports/" + token
+	}
+}
+
+// GET /admin/imports/reports/:token
+func DownloadImportReport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var report models.ImportReport
+	err := initialializers.DB.Collection("import_reports").FindOne(ctx, bson.M{"token": c.Param("token"), "expires_at": bson.M{"$gt": time.Now()}}).Decode(&report)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Report not found or expired"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch import report"})
+		return
+	}
+
+	xlsx, err := buildImportReport(report)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to build import report"})
+		return
+	}
+	defer xlsx.Close()
+
+	name := strings.TrimSuffix(report.FileName, filepath.Ext(report.FileName))
+	if name == "" {
+		name = report.Kind
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-errors.xlsx"`, name))
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	if err := xlsx.Write(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+// buildImportReport echoes the uploaded rows with an extra "errors" column and the bad cells highlighted
+func buildImportReport(report models.ImportReport) (*excelize.File, error) {
+	xlsx := excelize.NewFile()
+	sheet := "Sheet1"
+
+	width := len(report.Header)
+	for _, r := range report.Rows {
+		if len(r.Cells) > width {
+			width = len(r.Cells)
+		}
+	}
+
+	headerStyle, err := xlsx.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	badStyle, err := xlsx.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+		Font: &excelize.Font{Color: "9C0006"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	errorStyle, err := xlsx.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "9C0006"}, Alignment: &excelize.Alignment{WrapText: true}})
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, width+1)
+	for i := range header[:width] {
+		if i < len(report.Header) {
+			header[i] = report.Header[i]
+		} else {
+			header[i] = ""
+		}
+	}
+	header[width] = "errors"
+	if err := xlsx.SetSheetRow(sheet, "A1", &header); err != nil {
+		return nil, err
+	}
+	lastHeader, _ := excelize.CoordinatesToCellName(width+1, 1)
+	if err := xlsx.SetCellStyle(sheet, "A1", lastHeader, headerStyle); err != nil {
+		return nil, err
+	}
+
+	for i, r := range report.Rows {
+		rowNumber := i + 2
+		values := make([]any, width+1)
+		for j := range values[:width] {
+			if j < len(r.Cells) {
+				values[j] = r.Cells[j]
+			} else {
+				values[j] = ""
+			}
+		}
+		values[width] = strings.Join(r.Errors, "; ")
+
+		start, _ := excelize.CoordinatesToCellName(1, rowNumber)
+		if err := xlsx.SetSheetRow(sheet, start, &values); err != nil {
+			return nil, err
+		}
+		for _, column := range r.BadColumns {
+			if column > width {
+				continue
+			}
+			cell, _ := excelize.CoordinatesToCellName(column+1, rowNumber)
+			if err := xlsx.SetCellStyle(sheet, cell, cell, badStyle); err != nil {
+				return nil, err
+			}
+		}
+		if len(r.Errors) > 0 {
+			cell, _ := excelize.CoordinatesToCellName(width+1, rowNumber)
+			if err := xlsx.SetCellStyle(sheet, cell, cell, errorStyle); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	errorsColumn, _ := excelize.ColumnNumberToName(width + 1)
+	if err := xlsx.SetColWidth(sheet, errorsColumn, errorsColumn, 60); err != nil {
+		return nil, err
+	}
+	return xlsx, nil
+}
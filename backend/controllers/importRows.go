@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"lateslip/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// What an import would do with a row
+const (
+	rowNew       = "new"
+	rowChanged   = "changed"
+	rowUnchanged = "unchanged"
+	rowInvalid   = "invalid"
+)
+
+// importRow is one data row of an uploaded file after parsing, validation and
+// comparison with the database
+type importRow struct {
+	Row        int      `json:"row" bson:"row"` // row number in the sheet, header is row 1
+	Cells      []string `json:"cells" bson:"cells"`
+	Action     string   `json:"action" bson:"action"`
+	Errors     []string `json:"errors,omitempty" bson:"errors,omitempty"`
+	BadColumns []int    `json:"bad_columns,omitempty" bson:"bad_columns,omitempty"` // 0-based cells the errors refer to
+	Changes    []string `json:"changes,omitempty" bson:"changes,omitempty"`         // fields that differ from the database
+}
+
+// invalid marks the row as rejected, optionally pointing at the offending cells
+func (r *importRow) invalid(message string, columns ...int) {
+	r.Action = rowInvalid
+	r.Errors = append(r.Errors, message)
+	for _, column := range columns {
+		if column >= 0 {
+			r.BadColumns = append(r.BadColumns, column)
+		}
+	}
+}
+
+// importSummary counts rows per action
+type importSummary struct {
+	New       int `json:"new" bson:"new"`
+	Changed   int `json:"changed" bson:"changed"`
+	Unchanged int `json:"unchanged" bson:"unchanged"`
+	Invalid   int `json:"invalid" bson:"invalid"`
+	Removed   int `json:"removed,omitempty" bson:"removed,omitempty"`
+}
+
+func (s *importSummary) add(action string) {
+	switch action {
+	case rowNew:
+		s.New++
+	case rowChanged:
+		s.Changed++
+	case rowUnchanged:
+		s.Unchanged++
+	case rowInvalid:
+		s.Invalid++
+	}
+}
+
+// rowError is the part of an invalid row reported back to the uploader
+type rowError struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+// invalidRows lists every row that failed validation
+func invalidRows(rows []importRow) []rowError {
+	errors := []rowError{}
+	for _, r := range rows {
+		if r.Action == rowInvalid {
+			errors = append(errors, rowError{Row: r.Row, Errors: r.Errors})
+		}
+	}
+	return errors
+}
+
+func studentImportRows(rows []studentRow) []importRow {
+	result := make([]importRow, len(rows))
+	for i, r := range rows {
+		result[i] = r.importRow
+	}
+	return result
+}
+
+func studentIDs(students []models.Student) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(students))
+	for i, student := range students {
+		ids[i] = student.ID
+	}
+	return ids
+}
+
+func scheduleIDs(schedules []models.Schedule) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.ID
+	}
+	return ids
+}
+
+func scheduleImportRows(rows []scheduleRow) []importRow {
+	result := make([]importRow, len(rows))
+	for i, r := range rows {
+		result[i] = r.importRow
+	}
+	return result
+}
+
+// batchWriter sends bulk writes in batches of importBatchSize and reports the rows handled
+// after each batch
+type batchWriter struct {
+	collection *mongo.Collection
+	writes     []mongo.WriteModel
+	progress   progressFunc
+}
+
+// add queues a write for the row at position processed, sending the batch when it is full
+func (b *batchWriter) add(ctx context.Context, write mongo.WriteModel, processed int) error {
+	b.writes = append(b.writes, write)
+	if len(b.writes) < importBatchSize {
+		return nil
+	}
+	return b.flush(ctx, processed)
+}
+
+// flush sends what is queued and reports processed rows as done
+func (b *batchWriter) flush(ctx context.Context, processed int) error {
+	if len(b.writes) > 0 {
+		if _, err := b.collection.BulkWrite(ctx, b.writes); err != nil {
+			return err
+		}
+		b.writes = nil
+	}
+	b.progress(processed)
+	return nil
+}
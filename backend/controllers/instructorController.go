@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"context"
+	"lateslip/events"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// instructorNameFilter matches Schedule/LateSlip instructor names regardless of case and
+// surrounding spaces, as the timetable is typed by hand
+func instructorNameFilter(name string) bson.M {
+	return bson.M{"$regex": "^\\s*" + regexp.QuoteMeta(strings.TrimSpace(name)) + "\\s*$", "$options": "i"}
+}
+
+// findInstructor loads the account of the instructor making the request
+func findInstructor(ctx context.Context, c *gin.Context) (models.User, error) {
+	var instructor models.User
+	id, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		return instructor, err
+	}
+	err = initialializers.DB.Collection("users").FindOne(ctx, bson.M{"_id": id, "role": "instructor"}).Decode(&instructor)
+	return instructor, err
+}
+
+// POST /admin/instructors
+func CreateInstructor(c *gin.Context) {
+	type body struct {
+		Fullname       string `json:"fullname" binding:"required"`
+		Email          string `json:"email" binding:"required,email"`
+		Password       string `json:"password" binding:"required,min=8"`
+		InstructorName string `json:"instructorName" binding:"required"` // as written in the timetable
+	}
+	var b body
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	b.Email = models.NormalizeEmail(b.Email)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	userCollection := initialializers.DB.Collection("users")
+	err := userCollection.FindOne(ctx, bson.M{"email": b.Email}).Err()
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "User already exists"})
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Database error"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(b.Password), 10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Error hashing password"})
+		return
+	}
+
+	now := time.Now()
+	user := models.User{
+		ID:             primitive.NewObjectID(),
+		Fullname:       b.Fullname,
+		Email:          b.Email,
+		Password:       string(hashedPassword),
+		Role:           "instructor",
+		InstructorName: strings.TrimSpace(b.InstructorName),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if _, err := userCollection.InsertOne(ctx, user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": "User already exists"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Error creating user"})
+		return
+	}
+
+	// let the admin know when the name does not appear in the timetable yet
+	sessions, err := initialializers.DB.Collection("schedules").CountDocuments(ctx, bson.M{"instructor_name": instructorNameFilter(user.InstructorName)})
+	if err != nil {
+		c.Error(err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Instructor created successfully",
+		"user": gin.H{
+			"id":             user.ID.Hex(),
+			"fullname":       user.Fullname,
+			"email":          user.Email,
+			"role":           user.Role,
+			"instructorName": user.InstructorName,
+			"createdAt":      user.CreatedAt,
+		},
+		"sessions": sessions,
+	})
+}
+
+// GET /admin/instructors
+func GetInstructors(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"password": 0}).SetSort(bson.D{{Key: "fullname", Value: 1}})
+	cursor, err := initialializers.DB.Collection("users").Find(ctx, bson.M{"role": "instructor"}, opts)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch instructors"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	instructors := []gin.H{}
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			c.Error(err)
+			continue
+		}
+		instructors = append(instructors, gin.H{
+			"id":             user.ID.Hex(),
+			"fullname":       user.Fullname,
+			"email":          user.Email,
+			"instructorName": user.InstructorName,
+			"createdAt":      user.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "instructors": instructors})
+}
+
+// GET /instructor/lateslips?date=YYYY-MM-DD&used=true|false
+//
+// Approved slips for the instructor's sessions, by default those requested today.
+func GetInstructorLateSlips(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	instructor, err := findInstructor(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Instructor account not found"})
+		return
+	}
+
+	day := initialializers.CampusNow()
+	if date := c.Query("date"); date != "" {
+		day, err = time.ParseInLocation("2006-01-02", date, initialializers.CampusLocation)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	end := endOfCampusDay(day)
+
+	filter := bson.M{
+		"status":          "approved",
+		"instructor_name": instructorNameFilter(instructor.InstructorName),
+		"created_at":      bson.M{"$gte": end.AddDate(0, 0, -1), "$lt": end},
+	}
+	if value := c.Query("used"); value != "" {
+		used, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "used must be true or false"})
+			return
+		}
+		filter["used_at"] = bson.M{"$exists": used}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := initialializers.DB.Collection("lateslips").Find(ctx, filter, opts)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch late slips"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	lateSlips := []models.LateSlip{}
+	if err := cursor.All(ctx, &lateSlips); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to decode late slips"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "lateSlips": lateSlips})
+}
+
+// PUT /instructor/lateslips/:id/use
+//
+// Marks an approved slip as presented when the student walks in. A slip can be used once,
+// only by the instructor of its session and only while it is valid.
+func MarkLateSlipUsed(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid late slip ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	instructor, err := findInstructor(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Instructor account not found"})
+		return
+	}
+
+	lateSlipCollection := initialializers.DB.Collection("lateslips")
+	var lateSlip models.LateSlip
+	err = lateSlipCollection.FindOne(ctx, bson.M{"_id": id, "instructor_name": instructorNameFilter(instructor.InstructorName)}).Decode(&lateSlip)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Late slip not found for your sessions"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch late slip"})
+		return
+	}
+
+	now := time.Now()
+	if valid, reason := slipValidity(lateSlip, now); !valid {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": reason})
+		return
+	}
+
+	// guard against the slip being used twice at the same time
+	set := bson.M{"used_at": now, "used_by": instructor.ID.Hex(), "used_by_name": instructor.Fullname, "updated_at": now}
+	result, err := lateSlipCollection.UpdateOne(ctx, bson.M{"_id": id, "status": "approved", "used_at": bson.M{"$exists": false}}, bson.M{"$set": set})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to update late slip"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Late slip was already used"})
+		return
+	}
+	lateSlip.UsedAt = &now
+	lateSlip.UsedBy = instructor.ID.Hex()
+	lateSlip.UsedByName = instructor.Fullname
+	lateSlip.UpdatedAt = now
+
+	events.NotifyInstructors("LATE_SLIP_USED", lateSlip)
+	events.NotifyStudent(lateSlip.StudentID.Hex(), "Your late slip has been accepted by "+instructor.Fullname)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Late slip marked as used", "lateSlip": lateSlip})
+}
+
+// GET /instructor/ws
+func InstructorWebSocket(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	instructor, err := findInstructor(ctx, c)
+	cancel()
+	if err != nil || instructor.InstructorName == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Instructor account not found"})
+		return
+	}
+
+	c.Set("instructor_name", instructor.InstructorName)
+	events.WebSocketHandler(c)
+}
@@ -2,79 +2,74 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"lateslip/events"
 	"lateslip/initialializers"
+	"lateslip/mailtemplates"
 	"lateslip/models"
+	"lateslip/outbox"
 	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sendgrid/sendgrid-go"
-	"github.com/sendgrid/sendgrid-go/helpers/mail"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// sendEmail sends an email using SendGrid asynchronously
-func sendEmail(toEmail, subject, content string) {
-	go func() {
-		// Check if API key is set
-		apiKey := os.Getenv("SENDGRID_API_KEY")
-		if apiKey == "" {
-			log.Printf("SendGrid API key not found")
-			return
-		}
-
-		fromEmail := os.Getenv("SENDGRID_FROM_EMAIL")
-		if fromEmail == "" {
-			log.Printf("SendGrid from email not found")
-			return
-		}
-
-		from := mail.NewEmail("HeraldSync Late Slip System", fromEmail)
-		to := mail.NewEmail("Admin", toEmail)
-
-		// Create HTML content
-		htmlContent := fmt.Sprintf(`
-            <div style="font-family: Arial, sans-serif; padding: 20px;">
-                <h2>New Late Slip Request</h2>
-                <p>%s</p>
-                <p>This is an automated message from the HeraldSync Late Slip System.</p>
-            </div>
-        `, content)
-
-		message := mail.NewSingleEmail(from, subject, to, content, htmlContent)
-
-		// // Add custom headers
-		// message.SetHeader("List-Unsubscribe", "<mailto:unsubscribe@heraldsync.com>")
-		// message.SetHeader("Precedence", "bulk")
+var (
+	errQuotaReached   = errors.New("late slip quota reached")
+	errAlreadyDecided = errors.New("late slip already decided")
+)
 
-		client := sendgrid.NewSendClient(apiKey)
+// findStudentForUser loads the roster record that belongs to a student user account.
+// Accounts created before the link existed are matched by email and linked on the way.
+func findStudentForUser(ctx context.Context, userID primitive.ObjectID) (models.Student, error) {
+	var user models.User
+	var student models.Student
+	userCollection := initialializers.DB.Collection("users")
+	err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		return student, err
+	}
 
-		log.Printf("Attempting to send email to: %s", toEmail)
-		response, err := client.Send(message)
-		if err != nil {
-			log.Printf("Failed to send email: %v", err)
-			return
-		}
+	studentCollection := initialializers.DB.Collection("students")
+	if !user.StudentID.IsZero() {
+		err = studentCollection.FindOne(ctx, bson.M{"_id": user.StudentID}).Decode(&student)
+		return student, err
+	}
 
-		if response.StatusCode >= 300 {
-			log.Printf("Email failed with status %d: %s", response.StatusCode, response.Body)
-			return
-		}
+	err = studentCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&student)
+	if err != nil {
+		return student, err
+	}
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"student_id": student.ID}})
+	if err != nil {
+		log.Printf("Failed to link user %s to student %s: %v", user.ID.Hex(), student.StudentID, err)
+	}
+	return student, nil
+}
 
-		log.Printf("Email sent successfully! Status: %d", response.StatusCode)
-	}()
+// deciderFor returns the ID and name of the admin making the request
+func deciderFor(ctx context.Context, c *gin.Context) (string, string) {
+	userID := c.GetString("user_id")
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return userID, ""
+	}
+	var admin models.User
+	if err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"_id": id}).Decode(&admin); err != nil {
+		log.Printf("Failed to look up admin %s: %v", userID, err)
+		return userID, ""
+	}
+	return userID, admin.Fullname
 }
 
 func RequestLateSlip(c *gin.Context) {
-	//TODO: need to check if student's late slip request limit is reached or not (max is 4 per semester)
-	//If the limit is reached, return an error response
-
 	//get student ID from context and reason from request body
 	userId, exists := c.Get("user_id")
 	requestID := c.GetString("request_id")
@@ -90,7 +85,8 @@ func RequestLateSlip(c *gin.Context) {
 	}
 
 	type requestBody struct {
-		Reason string `json:"reason" binding:"required"`
+		Reason     string `json:"reason" binding:"required"`
+		ScheduleID string `json:"scheduleId"` // optional, the current class is used when empty
 	}
 	var body requestBody
 	if err := c.BindJSON(&body); err != nil {
@@ -98,57 +94,150 @@ func RequestLateSlip(c *gin.Context) {
 		return
 	}
 
-	//create a new late slip request
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
+	//check the student's late slip quota for the current semester
+	student, err := findStudentForUser(ctx, studentID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "No student record found for this account"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch student details"})
+		return
+	}
+	if student.Inactive {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "This student is no longer on the roster"})
+		return
+	}
+
+	limit := initialializers.LateSlipLimit(student.Level)
+	quotaReached := func(student models.Student) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success":   false,
+			"error":     fmt.Sprintf("Late slip limit reached for semester %s: %d of %d used, %d pending", student.Semester, student.LateSlipCount, limit, student.PendingSlipCount),
+			"limit":     limit,
+			"used":      student.LateSlipCount,
+			"pending":   student.PendingSlipCount,
+			"remaining": 0,
+		})
+	}
+	if student.LateSlipCount+student.PendingSlipCount >= limit {
+		quotaReached(student)
+		return
+	}
+
+	//find the class the student is late for
+	schedule, err := findScheduleForSlip(ctx, student, body.ScheduleID, time.Now())
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Schedule not found"})
+			return
+		}
+		if errors.Is(err, errScheduleNotForStudent) {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Schedule is not part of your semester"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+		return
+	}
+
+	//create a new late slip request
 	lateSlip := models.LateSlip{
-		ID:        primitive.NewObjectID(),
-		RequestID: requestID,
-		StudentID: studentID,
-		Reason:    body.Reason,
-		Status:    "pending",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:              primitive.NewObjectID(),
+		RequestID:       requestID,
+		StudentID:       studentID,
+		Reason:          body.Reason,
+		Status:          "pending",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+		StudentRecordID: student.ID,
+		RosterID:        student.StudentID,
+		StudentName:     student.Name,
+		Level:           student.Level,
+		Semester:        student.Semester,
+	}
+	applySchedule(&lateSlip, schedule)
+
+	//resolve who should hear about the new request
+	admins, err := adminRecipients(ctx)
+	if err != nil {
+		log.Printf("Failed to resolve admin recipients for late slip %s: %v", lateSlip.ID.Hex(), err)
+	}
+	messages, err := emailMessages(lateSlip.ID, mailtemplates.RequestSubmitted, admins, lateSlipEmailData(lateSlip))
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare notification email"})
+		return
 	}
 
-	//insert the late slip into the database
+	//hold a place in the quota, then insert the late slip and its notifications together
+	studentCollection := initialializers.DB.Collection("students")
 	lateSlipCollection := initialializers.DB.Collection("lateslips")
-	_, err = lateSlipCollection.InsertOne(ctx, lateSlip)
+	var remaining int
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		// one conditional update, so concurrent requests cannot both take the last place
+		var updated models.Student
+		err := studentCollection.FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"_id":      student.ID,
+				"semester": student.Semester,
+				"$expr":    bson.M{"$lt": bson.A{bson.M{"$add": bson.A{"$late_slip_count", bson.M{"$ifNull": bson.A{"$pending_slip_count", 0}}}}, limit}},
+			},
+			bson.M{"$inc": bson.M{"pending_slip_count": 1}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			return errQuotaReached
+		}
+		if err != nil {
+			return err
+		}
+		remaining = limit - updated.LateSlipCount - updated.PendingSlipCount
+
+		if _, err = lateSlipCollection.InsertOne(ctx, lateSlip); err == nil {
+			err = outbox.Enqueue(ctx, messages...)
+		}
+		if err != nil {
+			// give the place back when transactions are unavailable
+			if _, rollbackErr := studentCollection.UpdateOne(ctx, bson.M{"_id": student.ID}, bson.M{"$inc": bson.M{"pending_slip_count": -1}}); rollbackErr != nil {
+				log.Printf("Failed to roll back pending late slip count for student %s: %v", student.StudentID, rollbackErr)
+			}
+		}
+		return err
+	})
+	if errors.Is(err, errQuotaReached) {
+		// show the counts that refused the request
+		if err := studentCollection.FindOne(ctx, bson.M{"_id": student.ID}).Decode(&student); err != nil {
+			c.Error(err)
+		}
+		quotaReached(student)
+		return
+	}
 	if err != nil {
 		c.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create late slip"})
 		return
 	}
 
-	//TODO: send notification to admin
-	// This could be done via email, push notification, etc.
-	sendEmail(
-		"samreedmaharjan1899@gmail.com",
-		"New Late Slip Request Notification",
-		fmt.Sprintf(`
-        A new late slip request has been submitted with the following details:
-        
-        Student ID: %s
-        Reason: %s
-        Status: %s
-        Submitted: %s
-    `, studentID.Hex(), body.Reason, lateSlip.Status, lateSlip.CreatedAt.Format("Jan 2, 2006 3:04 PM")),
-	)
 	events.NotifyAdmins(
-		fmt.Sprintf("New late slip request from %s", studentID.Hex()),
+		fmt.Sprintf("New late slip request from %s", student.Name),
 		lateSlip,
 	)
 
 	//return the late slip
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Late slip created successfully", "lateSlip": lateSlip})
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Late slip created successfully", "lateSlip": lateSlip, "remaining": remaining})
 
 }
 
 func ApproveLateSlip(c *gin.Context) {
 	type Body struct {
 		LateSlipID string `json:"lateSlipId" binding:"required"`
-		StudentID  string `json:"studentId" binding:"required"`
+		StudentID  string `json:"studentId"` // optional, checked against the slip when given
 	}
 	var body Body
 	if err := c.BindJSON(&body); err != nil {
@@ -158,15 +247,9 @@ func ApproveLateSlip(c *gin.Context) {
 
 	lateSlipID, err := primitive.ObjectIDFromHex(body.LateSlipID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student ID"})
-		return
-	}
-	studentID, err := primitive.ObjectIDFromHex(body.StudentID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid late slip ID"})
 		return
 	}
-
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
@@ -189,11 +272,16 @@ func ApproveLateSlip(c *gin.Context) {
 		})
 		return
 	}
-	//TODO: Replace the User model with the Student model
-	UserCollection := initialializers.DB.Collection("users")
-	var student models.User
-	err = UserCollection.FindOne(ctx, bson.M{"_id": studentID}).Decode(&student)
+	if body.StudentID != "" && body.StudentID != lateSlip.StudentID.Hex() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Late slip does not belong to this student",
+		})
+		return
+	}
+	student, err := findStudentForUser(ctx, lateSlip.StudentID)
 	if err != nil {
+		c.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to fetch student details",
@@ -201,56 +289,115 @@ func ApproveLateSlip(c *gin.Context) {
 		return
 	}
 
-	//TODO: need to update the lateslip count logic rignt now
-	// --- should use student model instead of lateslip model
-
-	// // Increment late slip count
-	// _, err = StudentCollection.UpdateOne(
-	// 	ctx,
-	// 	bson.M{"_id": lateSlip.StudentID},
-	// 	bson.M{"$inc": bson.M{"lateSlipCount": 1}},
-	// )
-	// if err != nil {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{
-	// 		"success": false,
-	// 		"error":   "Failed to update student late slip count",
-	// 	})
-	// 	return
-	// }
-
 	// Update late slip status
+	now := time.Now()
+	validUntil := endOfCampusDay(now)
 	lateSlip.Status = "approved"
-	lateSlip.UpdatedAt = time.Now()
+	lateSlip.UpdatedAt = now
+	lateSlip.DecidedAt = &now
+	lateSlip.DecidedBy, lateSlip.DecidedByName = deciderFor(ctx, c)
+	lateSlip.ValidUntil = &validUntil
+
+	studentCollection := initialializers.DB.Collection("students")
+	limit := initialializers.LateSlipLimit(student.Level)
+	var remaining int
+
+	// A request of the current semester already holds a place, which turns from pending
+	// to used. Older requests take a place only while the student is under the limit.
+	quotaFilter := bson.M{"_id": student.ID, "late_slip_count": bson.M{"$lt": limit}}
+	quotaUpdate, quotaUndo := bson.M{"late_slip_count": 1}, bson.M{"late_slip_count": -1}
+	if lateSlip.Semester == student.Semester {
+		quotaFilter = bson.M{"_id": student.ID, "semester": student.Semester, "pending_slip_count": bson.M{"$gt": 0}}
+		quotaUpdate["pending_slip_count"], quotaUndo["pending_slip_count"] = -1, 1
+	}
 
-	//update the late slip in the database
-	_, err = lateSlipCollection.UpdateOne(ctx, bson.M{"_id": lateSlipID}, bson.M{"$set": lateSlip})
-	if err != nil {
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		var updated models.Student
+		err := studentCollection.FindOneAndUpdate(
+			ctx,
+			quotaFilter,
+			bson.M{"$inc": quotaUpdate},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			return errQuotaReached
+		}
+		if err != nil {
+			return err
+		}
+		remaining = limit - updated.LateSlipCount - updated.PendingSlipCount
+
+		//email the student, and warn them when they are running out of late slips
+		recipients := studentRecipient(ctx, lateSlip)
+		data := lateSlipEmailData(lateSlip)
+		data.Limit = limit
+		data.Remaining = remaining
+		messages, err := emailMessages(lateSlip.ID, mailtemplates.Approved, recipients, data)
+		if err == nil && remaining <= initialializers.QuotaWarningThreshold() {
+			var warnings []models.OutboxMessage
+			warnings, err = emailMessages(lateSlip.ID, mailtemplates.QuotaWarning, recipients, data)
+			messages = append(messages, warnings...)
+		}
+
+		//update the late slip in the database, guarding against a concurrent decision
+		if err == nil {
+			var update *mongo.UpdateResult
+			update, err = lateSlipCollection.UpdateOne(ctx, bson.M{"_id": lateSlipID, "status": "pending"}, bson.M{"$set": lateSlip})
+			if err == nil && update.MatchedCount == 0 {
+				err = errAlreadyDecided
+			}
+		}
+		if err != nil {
+			// give the slip back when transactions are unavailable, so the count matches the approved slips
+			if _, rollbackErr := studentCollection.UpdateOne(ctx, bson.M{"_id": student.ID}, bson.M{"$inc": quotaUndo}); rollbackErr != nil {
+				log.Printf("Failed to roll back late slip count for student %s: %v", student.StudentID, rollbackErr)
+			}
+			return err
+		}
+
+		return outbox.Enqueue(ctx, messages...)
+	})
+	switch {
+	case errors.Is(err, errQuotaReached):
+		c.JSON(http.StatusConflict, gin.H{
+			"success":   false,
+			"error":     fmt.Sprintf("Student has already used all %d late slips for semester %s", limit, student.Semester),
+			"limit":     limit,
+			"remaining": 0,
+		})
+		return
+	case errors.Is(err, errAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Late slip was already decided"})
+		return
+	case err != nil:
 		c.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update late slip"})
 		return
 	}
 
-	//TODO: send notification to student
-	// This could be done via email, push notification, etc.
-	sendEmail(
-		"bivekshrestha239@gmail.com",
-		"Late Slip Approval Notification",
-		fmt.Sprintf(`
-        Your late slip request has been approved.
-        
-        Late Slip ID: %s
-        Reason: %s
-        Status: %s
-        Approved: %s
-    `, lateSlip.ID.Hex(), lateSlip.Reason, lateSlip.Status, lateSlip.UpdatedAt.Format("Jan 2, 2006 3:04 PM")),
-	)
 	events.NotifyStudent(
-		studentID.Hex(),
+		lateSlip.StudentID.Hex(),
 		fmt.Sprintf("Your late slip request has been %s", lateSlip.Status),
 	)
+	events.NotifyInstructors("LATE_SLIP_APPROVED", lateSlip)
 
 	//return the late slip
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Late slip approved successfully", "lateSlip": lateSlip})
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Late slip approved successfully", "lateSlip": lateSlip, "remaining": remaining})
+}
+
+// lateSlipFilter narrows admin late slip lists by module, instructor or semester
+func lateSlipFilter(c *gin.Context) bson.M {
+	filter := bson.M{}
+	if moduleCode := c.Query("moduleCode"); moduleCode != "" {
+		filter["module_code"] = moduleCode
+	}
+	if instructor := c.Query("instructor"); instructor != "" {
+		filter["instructor_name"] = instructor
+	}
+	if semester := c.Query("semester"); semester != "" {
+		filter["semester"] = semester
+	}
+	return filter
 }
 
 func GetAllLateSlips(c *gin.Context) {
@@ -258,7 +405,7 @@ func GetAllLateSlips(c *gin.Context) {
 	defer cancel()
 
 	lateSlipCollection := initialializers.DB.Collection("lateslips")
-	cursor, err := lateSlipCollection.Find(ctx, bson.M{})
+	cursor, err := lateSlipCollection.Find(ctx, lateSlipFilter(c))
 	if err != nil {
 		c.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch late slips"})
@@ -281,8 +428,11 @@ func GetAllPendingLateSlip(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
+	filter := lateSlipFilter(c)
+	filter["status"] = "pending"
+
 	lateSlipCollection := initialializers.DB.Collection("lateslips")
-	cursor, err := lateSlipCollection.Find(ctx, bson.M{"status": "pending"})
+	cursor, err := lateSlipCollection.Find(ctx, filter)
 	if err != nil {
 		c.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch late slips"})
@@ -305,7 +455,7 @@ func GetAllPendingLateSlip(c *gin.Context) {
 func RejectLateSlip(c *gin.Context) {
 	type Body struct {
 		LateSlipID string `json:"lateSlipId" binding:"required"`
-		StudentID  string `json:"studentId" binding:"required"`
+		StudentID  string `json:"studentId"` // optional, checked against the slip when given
 	}
 	var body Body
 	if err := c.BindJSON(&body); err != nil {
@@ -318,12 +468,6 @@ func RejectLateSlip(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid late slip ID"})
 		return
 	}
-	studentID, err := primitive.ObjectIDFromHex(body.StudentID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student ID"})
-		return
-	}
-
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
@@ -346,63 +490,58 @@ func RejectLateSlip(c *gin.Context) {
 		})
 		return
 	}
-	//TODO: Replace the User model with the Student model
-	UserCollection := initialializers.DB.Collection("users")
-	var student models.User
-	err = UserCollection.FindOne(ctx, bson.M{"_id": studentID}).Decode(&student)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if body.StudentID != "" && body.StudentID != lateSlip.StudentID.Hex() {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "Failed to fetch student details",
+			"error":   "Late slip does not belong to this student",
 		})
 		return
 	}
-
-	//TODO: need to update the lateslip count logic rignt now
-	// --- should use student model instead of lateslip model
-
-	// // Increment late slip count
-	// _, err = StudentCollection.UpdateOne(
-	// 	ctx,
-	// 	bson.M{"_id": lateSlip.StudentID},
-	// 	bson.M{"$inc": bson.M{"lateSlipCount": 1}},
-	// )
-	// if err != nil {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{
-	// 		"success": false,
-	// 		"error":   "Failed to update student late slip count",
-	// 	})
-	// 	return
-	// }
-
-	// Update late slip status
+	// Rejected slips do not count towards the semester limit and give their place back
+	now := time.Now()
 	lateSlip.Status = "rejected"
-	lateSlip.UpdatedAt = time.Now()
+	lateSlip.UpdatedAt = now
+	lateSlip.DecidedAt = &now
+	lateSlip.DecidedBy, lateSlip.DecidedByName = deciderFor(ctx, c)
+
+	messages, err := emailMessages(lateSlip.ID, mailtemplates.Rejected, studentRecipient(ctx, lateSlip), lateSlipEmailData(lateSlip))
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare notification email"})
+		return
+	}
 
-	//update the late slip in the database
-	_, err = lateSlipCollection.UpdateOne(ctx, bson.M{"_id": lateSlipID}, bson.M{"$set": lateSlip})
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		//update the late slip in the database, guarding against a concurrent decision
+		update, err := lateSlipCollection.UpdateOne(ctx, bson.M{"_id": lateSlipID, "status": "pending"}, bson.M{"$set": lateSlip})
+		if err != nil {
+			return err
+		}
+		if update.MatchedCount == 0 {
+			return errAlreadyDecided
+		}
+		// free the place the request held, unless the student has moved to another semester
+		_, err = initialializers.DB.Collection("students").UpdateOne(ctx,
+			bson.M{"_id": lateSlip.StudentRecordID, "semester": lateSlip.Semester, "pending_slip_count": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"pending_slip_count": -1}},
+		)
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, messages...)
+	})
+	if errors.Is(err, errAlreadyDecided) {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Late slip was already decided"})
+		return
+	}
 	if err != nil {
 		c.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update late slip"})
 		return
 	}
 
-	//TODO: send notification to student
-	// This could be done via email, push notification, etc.
-	sendEmail(
-		"bivekshrestha239@gmail.com",
-		"Late Slip Rejection Notification",
-		fmt.Sprintf(`
-        Your late slip request has been rejected.
-        
-        Late Slip ID: %s
-        Reason: %s
-        Status: %s
-        Approved: %s
-    `, lateSlip.ID.Hex(), lateSlip.Reason, lateSlip.Status, lateSlip.UpdatedAt.Format("Jan 2, 2006 3:04 PM")),
-	)
 	events.NotifyStudent(
-		studentID.Hex(),
+		lateSlip.StudentID.Hex(),
 		fmt.Sprintf("Your late slip request has been %s", lateSlip.Status),
 	)
 
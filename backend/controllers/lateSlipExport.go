@@ -0,0 +1,410 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exportColumns is the header of the slip sheet and of the CSV export
+var exportColumns = []string{
+	"Slip ID", "Requested at", "Status", "Roster ID", "Student", "Email", "Level", "Semester",
+	"Module code", "Module name", "Room", "Instructor", "Class start", "Reason", "Updated at",
+}
+
+// exportRow is a late slip joined with the roster record of its student
+type exportRow struct {
+	models.LateSlip
+	Email string
+}
+
+func (r exportRow) values() []string {
+	updated := ""
+	if r.Status != "pending" {
+		updated = formatExportTime(r.UpdatedAt)
+	}
+	return []string{
+		r.ID.Hex(), formatExportTime(r.CreatedAt), r.Status, r.RosterID, r.StudentName, r.Email, r.Level, r.Semester,
+		r.ModuleCode, r.ModuleName, r.RoomName, r.InstructorName, r.ClassStartTime, r.Reason, updated,
+	}
+}
+
+// csvSafe quotes cells that spreadsheet programs would run as a formula, such as a
+// reason of "=HYPERLINK(...)", by prefixing them with an apostrophe
+func csvSafe(values []string) []string {
+	for i, value := range values {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			values[i] = "'" + value
+		}
+	}
+	return values
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(initialializers.CampusLocation).Format("2006-01-02 15:04")
+}
+
+// exportFilter builds the query from the from/to dates (campus time, to inclusive), status,
+// moduleCode and instructor. Semester and level are checked after the roster join, as slips
+// requested before those fields were copied onto them do not have them.
+func exportFilter(c *gin.Context) (bson.M, error) {
+	filter := lateSlipFilter(c)
+	delete(filter, "semester")
+
+	created := bson.M{}
+	if from := c.Query("from"); from != "" {
+		day, err := time.ParseInLocation("2006-01-02", from, initialializers.CampusLocation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+		}
+		created["$gte"] = day
+	}
+	if to := c.Query("to"); to != "" {
+		day, err := time.ParseInLocation("2006-01-02", to, initialializers.CampusLocation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+		}
+		created["$lt"] = day.AddDate(0, 0, 1)
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case "pending", "approved", "rejected":
+			filter["status"] = status
+		default:
+			return nil, fmt.Errorf("invalid status %q", status)
+		}
+	}
+	return filter, nil
+}
+
+// exportRows loads the matching slips and fills in roster details from the students
+// collection where the slip does not carry them
+func exportRows(ctx context.Context, filter bson.M, semester, level string) ([]exportRow, error) {
+	cursor, err := initialializers.DB.Collection("lateslips").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var lateSlips []models.LateSlip
+	if err := cursor.All(ctx, &lateSlips); err != nil {
+		return nil, err
+	}
+
+	// users that requested the slips, for slips without a roster link
+	userIDs := []primitive.ObjectID{}
+	for _, lateSlip := range lateSlips {
+		if lateSlip.StudentRecordID.IsZero() {
+			userIDs = append(userIDs, lateSlip.StudentID)
+		}
+	}
+	users := map[primitive.ObjectID]models.User{}
+	if len(userIDs) > 0 {
+		var found []models.User
+		cursor, err := initialializers.DB.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.All(ctx, &found); err != nil {
+			return nil, err
+		}
+		for _, user := range found {
+			users[user.ID] = user
+		}
+	}
+
+	studentIDs := []primitive.ObjectID{}
+	emails := []string{}
+	for _, lateSlip := range lateSlips {
+		if !lateSlip.StudentRecordID.IsZero() {
+			studentIDs = append(studentIDs, lateSlip.StudentRecordID)
+		} else if user, ok := users[lateSlip.StudentID]; ok {
+			if !user.StudentID.IsZero() {
+				studentIDs = append(studentIDs, user.StudentID)
+			} else {
+				emails = append(emails, user.Email)
+			}
+		}
+	}
+	var students []models.Student
+	cursor, err = initialializers.DB.Collection("students").Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"_id": bson.M{"$in": studentIDs}},
+		bson.M{"email": bson.M{"$in": emails}},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &students); err != nil {
+		return nil, err
+	}
+	byID := map[primitive.ObjectID]models.Student{}
+	byEmail := map[string]models.Student{}
+	for _, student := range students {
+		byID[student.ID] = student
+		byEmail[strings.ToLower(student.Email)] = student
+	}
+
+	rows := []exportRow{}
+	for _, lateSlip := range lateSlips {
+		student, found := byID[lateSlip.StudentRecordID]
+		if !found {
+			if user, ok := users[lateSlip.StudentID]; ok {
+				if student, found = byID[user.StudentID]; !found {
+					student, found = byEmail[strings.ToLower(user.Email)]
+				}
+			}
+		}
+
+		row := exportRow{LateSlip: lateSlip}
+		if found {
+			row.Email = student.Email
+			if row.RosterID == "" {
+				row.RosterID = student.StudentID
+			}
+			if row.StudentName == "" {
+				row.StudentName = student.Name
+			}
+			if row.Level == "" {
+				row.Level = student.Level
+			}
+			if row.Semester == "" {
+				row.Semester = student.Semester
+			}
+		}
+		if semester != "" && !strings.EqualFold(row.Semester, semester) {
+			continue
+		}
+		if level != "" && !strings.EqualFold(row.Level, level) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// slipCounts counts slips per status
+type slipCounts struct {
+	Total, Approved, Pending, Rejected int
+}
+
+func (s *slipCounts) add(status string) {
+	s.Total++
+	switch status {
+	case "approved":
+		s.Approved++
+	case "pending":
+		s.Pending++
+	case "rejected":
+		s.Rejected++
+	}
+}
+
+// GET /admin/lateslips/export?format=xlsx|csv&from=&to=&status=&semester=&level=
+func ExportLateSlips(c *gin.Context) {
+	filter, err := exportFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "xlsx"))
+	if format != "xlsx" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "format must be xlsx or csv"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	rows, err := exportRows(ctx, filter, c.Query("semester"), c.Query("level"))
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch late slips"})
+		return
+	}
+
+	name := "lateslips-" + initialializers.CampusNow().Format("2006-01-02")
+	if format == "csv" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, name))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		writer := csv.NewWriter(c.Writer)
+		writer.Write(exportColumns)
+		for _, row := range rows {
+			writer.Write(csvSafe(row.values()))
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	xlsx, err := buildLateSlipExport(rows)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to build export"})
+		return
+	}
+	defer xlsx.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, name))
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	if err := xlsx.Write(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+// buildLateSlipExport writes the slips to a "Late slips" sheet and their counts per student
+// and per module to a "Summary" sheet
+func buildLateSlipExport(rows []exportRow) (*excelize.File, error) {
+	xlsx := excelize.NewFile()
+	slipsSheet, summarySheet := "Late slips", "Summary"
+	if err := xlsx.SetSheetName("Sheet1", slipsSheet); err != nil {
+		return nil, err
+	}
+	if _, err := xlsx.NewSheet(summarySheet); err != nil {
+		return nil, err
+	}
+
+	headerStyle, err := xlsx.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	setRow := func(sheet string, rowNumber int, values []any, style int) error {
+		start, _ := excelize.CoordinatesToCellName(1, rowNumber)
+		if err := xlsx.SetSheetRow(sheet, start, &values); err != nil {
+			return err
+		}
+		if style == 0 {
+			return nil
+		}
+		end, _ := excelize.CoordinatesToCellName(len(values), rowNumber)
+		return xlsx.SetCellStyle(sheet, start, end, style)
+	}
+
+	header := make([]any, len(exportColumns))
+	for i, column := range exportColumns {
+		header[i] = column
+	}
+	if err := setRow(slipsSheet, 1, header, headerStyle); err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		cells := row.values()
+		values := make([]any, len(cells))
+		for j, cell := range cells {
+			values[j] = cell
+		}
+		if err := setRow(slipsSheet, i+2, values, 0); err != nil {
+			return nil, err
+		}
+	}
+	if err := xlsx.AutoFilter(slipsSheet, "A1:"+lastCell(len(exportColumns), len(rows)+1), nil); err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetPanes(slipsSheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+
+	// per-student and per-module counts, one table under the other
+	type studentKey struct{ rosterID, name, level, semester string }
+	type moduleKey struct{ code, name string }
+	byStudent := map[studentKey]*slipCounts{}
+	byModule := map[moduleKey]*slipCounts{}
+	for _, row := range rows {
+		sk := studentKey{row.RosterID, row.StudentName, row.Level, row.Semester}
+		if byStudent[sk] == nil {
+			byStudent[sk] = &slipCounts{}
+		}
+		byStudent[sk].add(row.Status)
+
+		mk := moduleKey{row.ModuleCode, row.ModuleName}
+		if byModule[mk] == nil {
+			byModule[mk] = &slipCounts{}
+		}
+		byModule[mk].add(row.Status)
+	}
+
+	students := make([]studentKey, 0, len(byStudent))
+	for key := range byStudent {
+		students = append(students, key)
+	}
+	sort.Slice(students, func(i, j int) bool {
+		if students[i].rosterID != students[j].rosterID {
+			return students[i].rosterID < students[j].rosterID
+		}
+		return students[i].name < students[j].name
+	})
+	modules := make([]moduleKey, 0, len(byModule))
+	for key := range byModule {
+		modules = append(modules, key)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].code < modules[j].code })
+
+	rowNumber := 1
+	if err := setRow(summarySheet, rowNumber, []any{"Per student"}, headerStyle); err != nil {
+		return nil, err
+	}
+	rowNumber++
+	if err := setRow(summarySheet, rowNumber, []any{"Roster ID", "Student", "Level", "Semester", "Total", "Approved", "Pending", "Rejected"}, headerStyle); err != nil {
+		return nil, err
+	}
+	for _, key := range students {
+		counts := byStudent[key]
+		rowNumber++
+		if err := setRow(summarySheet, rowNumber, []any{key.rosterID, key.name, key.level, key.semester, counts.Total, counts.Approved, counts.Pending, counts.Rejected}, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	rowNumber += 2
+	if err := setRow(summarySheet, rowNumber, []any{"Per module"}, headerStyle); err != nil {
+		return nil, err
+	}
+	rowNumber++
+	if err := setRow(summarySheet, rowNumber, []any{"Module code", "Module name", "", "", "Total", "Approved", "Pending", "Rejected"}, headerStyle); err != nil {
+		return nil, err
+	}
+	for _, key := range modules {
+		counts := byModule[key]
+		code := key.code
+		if code == "" {
+			code = "(no module)"
+		}
+		rowNumber++
+		if err := setRow(summarySheet, rowNumber, []any{code, key.name, "", "", counts.Total, counts.Approved, counts.Pending, counts.Rejected}, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := xlsx.SetColWidth(slipsSheet, "A", "O", 16); err != nil {
+		return nil, err
+	}
+	if err := xlsx.SetColWidth(summarySheet, "A", "D", 18); err != nil {
+		return nil, err
+	}
+	return xlsx, nil
+}
+
+func lastCell(columns, rows int) string {
+	cell, _ := excelize.CoordinatesToCellName(columns, rows)
+	return cell
+}
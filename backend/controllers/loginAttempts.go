@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Reasons a login attempt failed, as recorded in login_attempts
+const (
+	loginWrongPassword = "wrong_password"
+	loginUnknownUser   = "unknown_user"
+	loginThrottled     = "throttled"
+	loginLocked        = "locked"
+	loginDisabled      = "disabled"
+	loginUnverified    = "unverified"
+	loginWrongPortal   = "wrong_portal"
+)
+
+// loginPortal is the login endpoint used, e.g. "student" for /student/login
+func loginPortal(c *gin.Context) string {
+	return strings.SplitN(strings.Trim(c.FullPath(), "/"), "/", 2)[0]
+}
+
+// recordLogin writes the audit record of a login attempt
+func recordLogin(c *gin.Context, email string, user *models.User, success bool, reason string) {
+	attempt := models.LoginAttempt{
+		ID:        primitive.NewObjectID(),
+		Email:     models.NormalizeEmail(email),
+		Portal:    loginPortal(c),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if user != nil {
+		attempt.UserID = user.ID.Hex()
+	}
+	if _, err := initialializers.DB.Collection("login_attempts").InsertOne(c.Request.Context(), attempt); err != nil {
+		c.Error(err)
+	}
+}
+
+// refuseThrottledLogin answers 429 when the account or the IP address has failed too
+// often recently, and reports whether it did
+func refuseThrottledLogin(c *gin.Context, email string) bool {
+	wait, locked, err := initialializers.LoginGuard.Check(c.Request.Context(), email, c.ClientIP(), time.Now())
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Database error"})
+		return true
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	message := fmt.Sprintf("Too many failed attempts, try again in %d seconds", seconds)
+	reason := loginThrottled
+	if locked {
+		message = fmt.Sprintf("Too many failed attempts, login is locked for %d minutes", int(math.Ceil(wait.Minutes())))
+		reason = loginLocked
+	}
+	recordLogin(c, email, nil, false, reason)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "error": message, "retryAfter": seconds})
+	return true
+}
+
+// loginFailed counts a wrong email or password towards throttling and records it
+func loginFailed(c *gin.Context, email string, user *models.User, reason string) {
+	if err := initialializers.LoginGuard.Fail(c.Request.Context(), email, c.ClientIP(), time.Now()); err != nil {
+		c.Error(err)
+	}
+	recordLogin(c, email, user, false, reason)
+}
+
+// loginSucceeded clears the account's failures and records the login
+func loginSucceeded(c *gin.Context, email string, user *models.User) {
+	if err := initialializers.LoginGuard.Succeed(c.Request.Context(), email); err != nil {
+		c.Error(err)
+	}
+	recordLogin(c, email, user, true, "")
+}
+
+// GET /admin/login-attempts?email=&ip=&success=true|false
+func GetLoginAttempts(c *gin.Context) {
+	filter := bson.M{}
+	if email := models.NormalizeEmail(c.Query("email")); email != "" {
+		filter["email"] = email
+	}
+	if ip := c.Query("ip"); ip != "" {
+		filter["ip"] = ip
+	}
+	if value := c.Query("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "success must be true or false"})
+			return
+		}
+		filter["success"] = success
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(200)
+	cursor, err := initialializers.DB.Collection("login_attempts").Find(ctx, filter, opts)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch login attempts"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	attempts := []models.LoginAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch login attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "attempts": attempts})
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lateslip/initialializers"
+	"lateslip/loginguard"
+	"lateslip/mailtemplates"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// newMockDB runs the handlers of a test against a mock deployment that answers with
+// the responses queued by mt.AddMockResponses
+func newMockDB(t *testing.T) *mtest.T {
+	gin.SetMode(gin.TestMode)
+	if err := mailtemplates.Load(""); err != nil {
+		t.Fatal(err)
+	}
+	return mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+}
+
+// useMockDB points initialializers.DB at mt's deployment and gives the test fresh
+// login and verification throttles
+func useMockDB(mt *mtest.T) {
+	db, logins, verification := initialializers.DB, initialializers.LoginGuard, initialializers.VerificationGuard
+	initialializers.DB = mt.DB
+	initialializers.LoginGuard = &loginguard.Guard{Store: loginguard.NewMemoryStore(), Policy: loginguard.DefaultPolicy}
+	initialializers.VerificationGuard = &loginguard.Guard{Store: loginguard.NewMemoryStore(), Policy: loginguard.VerificationPolicy, Scope: "verify:"}
+	mt.Cleanup(func() {
+		initialializers.DB, initialializers.LoginGuard, initialializers.VerificationGuard = db, logins, verification
+	})
+}
+
+// serveJSON sends body as JSON to handler mounted at route, from testClientIP
+func serveJSON(t *testing.T, handler gin.HandlerFunc, method, route, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Handle(method, route, handler)
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = testClientIP + ":40000"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+const testClientIP = "192.0.2.10"
+
+// startedCommands lists the names of the commands the handler sent, in order
+func startedCommands(mt *mtest.T) []string {
+	var names []string
+	for _, event := range mt.GetAllStartedEvents() {
+		names = append(names, event.CommandName)
+	}
+	return names
+}
+
+// responseOK is the reply to a write command that matched and changed n documents
+func responseOK(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, status, w.Body.String())
+	}
+}
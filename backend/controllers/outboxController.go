@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"lateslip/outbox"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /admin/outbox?status=dead
+//
+// Bodies are left out, they can hold login links and codes.
+func GetOutboxMessages(c *gin.Context) {
+	filter := bson.M{}
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case outbox.StatusPending, outbox.StatusSending, outbox.StatusSent, outbox.StatusDead:
+			filter["status"] = status
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := initialializers.DB.Collection("outbox").Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(200).SetProjection(bson.M{"text": 0, "html": 0}),
+	)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outbox messages"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	messages := []models.OutboxMessage{}
+	if err = cursor.All(ctx, &messages); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode outbox messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "messages": messages})
+}
+
+// POST /admin/outbox/:id/replay
+func ReplayOutboxMessage(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	replayed, err := outbox.Replay(ctx, id)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay outbox message"})
+		return
+	}
+	if !replayed {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "No failed or sent message with this ID; emails carrying a login link or code cannot be replayed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Message queued for delivery"})
+}
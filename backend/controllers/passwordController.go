@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"lateslip/initialializers"
+	"lateslip/mailtemplates"
+	"lateslip/models"
+	"lateslip/outbox"
+	"lateslip/sessions"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetLifetime is how long a password reset link can be used
+const resetLifetime = time.Hour
+
+// passwordError is a password change refused for a reason the caller can fix
+type passwordError struct {
+	status  int
+	message string
+}
+
+func (e *passwordError) Error() string { return e.message }
+
+// setPassword stores the new password and ends every session of the user; called inside
+// a transaction so both happen or neither does
+func setPassword(ctx context.Context, userID primitive.ObjectID, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = initialializers.DB.Collection("users").UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"password":            string(hashedPassword),
+		"password_changed_at": now,
+		"updated_at":          now,
+	}})
+	if err != nil {
+		return err
+	}
+	return sessions.RevokeUser(ctx, userID.Hex())
+}
+
+// POST /auth/password/forgot
+//
+// Emails a reset link. The response is the same whether or not the address has an
+// account, so it cannot be used to find out who is registered.
+func ForgotPassword(c *gin.Context) {
+	type body struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	var b body
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	response := gin.H{"success": true, "message": "If the address has an account, a reset link has been sent to it"}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"email": models.NormalizeEmail(b.Email)}).Decode(&user)
+	if err == mongo.ErrNoDocuments || (err == nil && user.Disabled) {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Database error"})
+		return
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create reset link"})
+		return
+	}
+
+	now := time.Now()
+	reset := models.PasswordReset{
+		ID:          primitive.NewObjectID(),
+		TokenHash:   hashToken(token),
+		UserID:      user.ID,
+		RequestedIP: c.ClientIP(),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(resetLifetime),
+	}
+	messages, err := emailMessages(primitive.NilObjectID, mailtemplates.PasswordReset, []recipient{{Name: user.Fullname, Email: user.Email}}, mailtemplates.Data{
+		Link:      initialializers.AppBaseURL + "/reset-password?token=" + token,
+		ExpiresAt: reset.ExpiresAt.In(initialializers.CampusLocation),
+	})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to render reset email"})
+		return
+	}
+
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		// only the newest link works
+		resetCollection := initialializers.DB.Collection("password_resets")
+		_, err := resetCollection.UpdateMany(ctx,
+			bson.M{"user_id": user.ID, "used_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"expires_at": now}},
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := resetCollection.InsertOne(ctx, reset); err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, messages...)
+	})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create reset link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// POST /auth/password/reset
+func ResetPassword(c *gin.Context) {
+	type body struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+	var b body
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	err := initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		var reset models.PasswordReset
+		err := initialializers.DB.Collection("password_resets").FindOneAndUpdate(ctx, bson.M{
+			"token_hash": hashToken(b.Token),
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+		}, bson.M{"$set": bson.M{"used_at": now}}).Decode(&reset)
+		if err == mongo.ErrNoDocuments {
+			return &passwordError{http.StatusBadRequest, "Reset link is invalid or has expired"}
+		}
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"_id": reset.UserID}).Decode(&user); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return &passwordError{http.StatusBadRequest, "Reset link is invalid or has expired"}
+			}
+			return err
+		}
+		if user.Disabled {
+			return &passwordError{http.StatusForbidden, "This account has been disabled"}
+		}
+		return setPassword(ctx, user.ID, b.Password)
+	})
+	if err != nil {
+		var refused *passwordError
+		if errors.As(err, &refused) {
+			c.JSON(refused.status, gin.H{"success": false, "error": refused.message})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Password has been reset, please log in again"})
+}
+
+// PUT /auth/password
+//
+// Changes the signed-in user's password. All sessions end, the caller gets a new one.
+func ChangePassword(c *gin.Context) {
+	type body struct {
+		CurrentPassword string `json:"currentPassword" binding:"required"`
+		NewPassword     string `json:"newPassword" binding:"required,min=8"`
+	}
+	var b body
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "User not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Database error"})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(b.CurrentPassword)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Current password is incorrect"})
+		return
+	}
+	if b.NewPassword == b.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "New password must be different"})
+		return
+	}
+
+	err = initialializers.WithTransaction(ctx, func(ctx context.Context) error {
+		return setPassword(ctx, user.ID, b.NewPassword)
+	})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to change password"})
+		return
+	}
+
+	tokens, err := sessions.Start(ctx, user, sessionClient(c))
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "Password changed, please log in again"})
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse("Password changed", tokens))
+}
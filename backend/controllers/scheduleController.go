@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errScheduleNotForStudent = errors.New("schedule is not part of the student's semester")
+	errInvalidScheduleID     = errors.New("invalid schedule ID")
+)
+
+// findScheduleForSlip returns the class a late slip is for. An explicit schedule ID is
+// checked against the student's semester; otherwise the class running right now for the
+// student's semester is used. A nil schedule means no class could be matched.
+func findScheduleForSlip(ctx context.Context, student models.Student, scheduleID string, now time.Time) (*models.Schedule, error) {
+	schedulesCollection := initialializers.DB.Collection("schedules")
+
+	if scheduleID != "" {
+		// any malformed ID, not only one of the wrong length, is a bad reference
+		id, err := primitive.ObjectIDFromHex(scheduleID)
+		if err != nil {
+			return nil, errInvalidScheduleID
+		}
+		var schedule models.Schedule
+		if err := schedulesCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&schedule); err != nil {
+			return nil, err
+		}
+		if schedule.Semester != models.NormalizeSemester(student.Semester) {
+			return nil, errScheduleNotForStudent
+		}
+		return &schedule, nil
+	}
+
+	now = now.In(initialializers.CampusLocation)
+	sessions, err := schedulesForDay(ctx, student.Semester, now.Weekday())
+	if err != nil {
+		return nil, err
+	}
+	return currentSession(sessions, now), nil
+}
+
+// currentSession picks the session running at now, which must be in campus time. When
+// sessions overlap the one that started last wins, as that is the class the student is
+// late for; equal starts fall back to the module code so the choice does not depend on
+// the order the database returns them in.
+func currentSession(sessions []models.Schedule, now time.Time) *models.Schedule {
+	var current *models.Schedule
+	for i := range sessions {
+		session := &sessions[i]
+		if !session.ActiveAt(now) {
+			continue
+		}
+		if current == nil || session.StartMinute > current.StartMinute ||
+			(session.StartMinute == current.StartMinute && session.ModuleCode < current.ModuleCode) {
+			current = session
+		}
+	}
+	return current
+}
+
+// schedulesForDay returns a semester's sessions on a weekday, ordered by start time
+func schedulesForDay(ctx context.Context, semester string, weekday time.Weekday) ([]models.Schedule, error) {
+	return findSchedules(ctx, bson.M{"semester": models.NormalizeSemester(semester), "weekday": weekday})
+}
+
+func findSchedules(ctx context.Context, filter bson.M) ([]models.Schedule, error) {
+	cursor, err := initialializers.DB.Collection("schedules").Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "weekday", Value: 1}, {Key: "start_minute", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	schedules := []models.Schedule{}
+	err = cursor.All(ctx, &schedules)
+	return schedules, err
+}
+
+// applySchedule copies the class details onto the late slip
+func applySchedule(lateSlip *models.LateSlip, schedule *models.Schedule) {
+	if schedule == nil {
+		return
+	}
+	lateSlip.ScheduleID = schedule.ID
+	lateSlip.ModuleCode = schedule.ModuleCode
+	lateSlip.ModuleName = schedule.ModuleName
+	lateSlip.RoomName = schedule.RoomName
+	lateSlip.InstructorName = schedule.InstructorName
+	lateSlip.ClassStartTime = schedule.StartTime
+}
+
+// isNotFound reports whether err means a referenced document does not exist
+func isNotFound(err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, errInvalidScheduleID)
+}
+
+// scheduleBody is the editable part of a schedule for the admin CRUD endpoints
+type scheduleBody struct {
+	ModuleCode     string `json:"module_code" binding:"required"`
+	ModuleName     string `json:"module_name" binding:"required"`
+	StartTime      string `json:"start_time" binding:"required"`
+	EndTime        string `json:"end_time" binding:"required"`
+	Day            string `json:"day" binding:"required"`
+	RoomName       string `json:"room_name"`
+	InstructorName string `json:"instructor_name"`
+	Semester       string `json:"semester" binding:"required"`
+}
+
+func (b scheduleBody) apply(schedule *models.Schedule) error {
+	schedule.ModuleCode = strings.TrimSpace(b.ModuleCode)
+	schedule.ModuleName = strings.TrimSpace(b.ModuleName)
+	schedule.StartTime = b.StartTime
+	schedule.EndTime = b.EndTime
+	schedule.Day = b.Day
+	schedule.RoomName = strings.TrimSpace(b.RoomName)
+	schedule.InstructorName = strings.TrimSpace(b.InstructorName)
+	schedule.Semester = strings.TrimSpace(b.Semester)
+	return schedule.Normalize()
+}
+
+// GET /admin/schedules?semester=&day=&moduleCode=&room=&instructor=
+func GetSchedules(c *gin.Context) {
+	filter := bson.M{}
+	if semester := c.Query("semester"); semester != "" {
+		filter["semester"] = models.NormalizeSemester(semester)
+	}
+	if day := c.Query("day"); day != "" {
+		weekday, err := models.ParseWeekday(day)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter["weekday"] = weekday
+	}
+	if moduleCode := c.Query("moduleCode"); moduleCode != "" {
+		filter["module_code"] = models.NormalizeModuleCode(moduleCode)
+	}
+	if room := c.Query("room"); room != "" {
+		filter["room_name"] = room
+	}
+	if instructor := c.Query("instructor"); instructor != "" {
+		filter["instructor_name"] = instructor
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	schedules, err := findSchedules(ctx, filter)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "schedules": schedules})
+}
+
+// GET /student/schedule?day=
+func GetStudentSchedule(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	student, err := findStudentForUser(ctx, userID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "No student record found for this account"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch student details"})
+		return
+	}
+
+	var schedules []models.Schedule
+	if day := c.Query("day"); day != "" {
+		weekday, parseErr := models.ParseWeekday(day)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": parseErr.Error()})
+			return
+		}
+		schedules, err = schedulesForDay(ctx, student.Semester, weekday)
+	} else {
+		schedules, err = findSchedules(ctx, bson.M{"semester": models.NormalizeSemester(student.Semester)})
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "semester": student.Semester, "schedules": schedules})
+}
+
+// GET /admin/schedules/:id
+func GetSchedule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var schedule models.Schedule
+	err = initialializers.DB.Collection("schedules").FindOne(ctx, bson.M{"_id": id}).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Schedule not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "schedule": schedule})
+}
+
+// POST /admin/schedules
+func CreateSchedule(c *gin.Context) {
+	var body scheduleBody
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule := models.Schedule{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := body.apply(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, err := initialializers.DB.Collection("schedules").InsertOne(ctx, schedule); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": "A session for this module already starts at this time in this semester"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule created successfully", "schedule": schedule})
+}
+
+// PUT /admin/schedules/:id
+func UpdateSchedule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	var body scheduleBody
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	schedulesCollection := initialializers.DB.Collection("schedules")
+	var schedule models.Schedule
+	if err := schedulesCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&schedule); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Schedule not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+		return
+	}
+
+	if err := body.apply(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	schedule.UpdatedAt = time.Now()
+
+	if _, err := schedulesCollection.ReplaceOne(ctx, bson.M{"_id": id}, schedule); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": "A session for this module already starts at this time in this semester"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule updated successfully", "schedule": schedule})
+}
+
+// DELETE /admin/schedules/:id
+func DeleteSchedule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	result, err := initialializers.DB.Collection("schedules").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule deleted successfully"})
+}
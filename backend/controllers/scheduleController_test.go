@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"lateslip/models"
+	"testing"
+	"time"
+)
+
+func TestCurrentSession(t *testing.T) {
+	session := func(module string, start, end int) models.Schedule {
+		return models.Schedule{ModuleCode: module, Weekday: time.Monday, StartMinute: start, EndMinute: end}
+	}
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC)
+	}
+	sessions := []models.Schedule{
+		session("CS101", 9*60, 11*60),
+		session("CS102", 10*60, 12*60),
+		session("MA101", 10*60, 11*60),
+		session("PH101", 13*60, 14*60),
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"one session running", monday(9, 30), "CS101"},
+		{"latest start wins", monday(10, 15), "CS102"},
+		{"ended sessions do not count", monday(11, 0), "CS102"},
+		{"between sessions", monday(12, 30), ""},
+		{"other weekday", monday(9, 30).AddDate(0, 0, 1), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := currentSession(sessions, tt.now)
+			if (got == nil && tt.want != "") || (got != nil && got.ModuleCode != tt.want) {
+				t.Fatalf("currentSession() = %+v, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// the result does not depend on the order the sessions come in
+	reversed := []models.Schedule{sessions[3], sessions[2], sessions[1], sessions[0]}
+	if got := currentSession(reversed, monday(10, 15)); got == nil || got.ModuleCode != "CS102" {
+		t.Fatalf("currentSession() of reversed sessions = %+v, want CS102", got)
+	}
+}
@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// scheduleImportStats counts what an import did to the schedules collection
+type scheduleImportStats struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+}
+
+// scheduleRow is a parsed timetable row
+type scheduleRow struct {
+	importRow `bson:",inline"`
+	Schedule  models.Schedule `json:"schedule" bson:"schedule"`
+}
+
+// scheduleKey is the natural key of a session: module code + day + start time + semester
+func scheduleKey(s models.Schedule) string {
+	return fmt.Sprintf("%s|%d|%d|%s",
+		models.NormalizeModuleCode(s.ModuleCode), s.Weekday, s.StartMinute, models.NormalizeSemester(s.Semester))
+}
+
+// scheduleChanges lists the non-key fields that differ between two sessions
+func scheduleChanges(current, imported models.Schedule) []string {
+	var changes []string
+	if current.ModuleName != imported.ModuleName {
+		changes = append(changes, "module_name")
+	}
+	if current.EndMinute != imported.EndMinute {
+		changes = append(changes, "end_time")
+	}
+	if current.RoomName != imported.RoomName {
+		changes = append(changes, "room_name")
+	}
+	if current.InstructorName != imported.InstructorName {
+		changes = append(changes, "instructor_name")
+	}
+	return changes
+}
+
+// parseScheduleRows turns the timetable columns (module code, module name, start, end,
+// day, room, instructor, semester) into sessions, recording problems on each row
+func parseScheduleRows(rows [][]string) []scheduleRow {
+	validator := validator.New()
+	var parsed []scheduleRow
+	seen := map[string]int{}
+	for i, row := range rows[1:] { // Skip header row
+		if len(row) == 0 {
+			r := scheduleRow{importRow: importRow{Row: i + 2, Cells: row}}
+			r.invalid("expected 8 columns, got 0", 0)
+			parsed = append(parsed, r)
+			continue
+		}
+		// spreadsheets drop trailing empty cells, so a blank semester shortens the row;
+		// pad it and let the checks below name the missing field
+		if len(row) < 8 {
+			padded := make([]string, 8)
+			copy(padded, row)
+			row = padded
+		}
+		r := scheduleRow{importRow: importRow{Row: i + 2, Cells: row}}
+
+		r.Schedule = models.Schedule{
+			ModuleCode:     strings.TrimSpace(row[0]),
+			ModuleName:     strings.TrimSpace(row[1]),
+			StartTime:      row[2],
+			EndTime:        row[3],
+			Day:            row[4],
+			RoomName:       strings.TrimSpace(row[5]),
+			InstructorName: strings.TrimSpace(row[6]),
+			Semester:       strings.TrimSpace(row[7]),
+		}
+
+		// Validate the schedule struct
+		if err := validator.Struct(r.Schedule); err != nil {
+			r.invalid(err.Error())
+		}
+		for column, field := range []string{"module code", "module name"} {
+			if strings.TrimSpace(row[column]) == "" {
+				r.invalid("missing "+field, column)
+			}
+		}
+		if strings.TrimSpace(row[7]) == "" {
+			r.invalid("missing semester", 7)
+		}
+
+		// Parse the day and times into the structured fields, pointing at the cell that is wrong
+		_, dayErr := models.ParseWeekday(row[4])
+		start, startErr := models.ParseClock(row[2])
+		end, endErr := models.ParseClock(row[3])
+		switch {
+		case dayErr != nil || startErr != nil || endErr != nil:
+			for column, err := range []error{2: startErr, 3: endErr, 4: dayErr} {
+				if err != nil {
+					r.invalid(err.Error(), column)
+				}
+			}
+		case end <= start:
+			r.invalid("end time is not after start time", 2, 3)
+		default:
+			if err := r.Schedule.Normalize(); err != nil {
+				r.invalid(err.Error())
+			}
+		}
+
+		// The same session twice in one file would make the upsert ambiguous
+		if r.Action != rowInvalid {
+			key := scheduleKey(r.Schedule)
+			if previous, exists := seen[key]; exists {
+				r.invalid("duplicate session, already given in row "+strconv.Itoa(previous), 0, 2, 4, 7)
+			} else {
+				seen[key] = r.Row
+			}
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed
+}
+
+// planScheduleImport compares each valid row with the stored sessions of its semester and
+// sets its action. With replaceSemester it also returns the stored sessions that the file
+// no longer contains.
+func planScheduleImport(ctx context.Context, rows []scheduleRow, replaceSemester bool) ([]models.Schedule, error) {
+	semesters := map[string]bool{}
+	for _, r := range rows {
+		if r.Action != rowInvalid {
+			semesters[r.Schedule.Semester] = true
+		}
+	}
+	semesterList := make([]string, 0, len(semesters))
+	for semester := range semesters {
+		semesterList = append(semesterList, semester)
+	}
+
+	existing, err := findSchedules(ctx, bson.M{"semester": bson.M{"$in": semesterList}})
+	if err != nil {
+		return nil, err
+	}
+	existingByKey := make(map[string]models.Schedule, len(existing))
+	for _, schedule := range existing {
+		existingByKey[scheduleKey(schedule)] = schedule
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	for i := range rows {
+		r := &rows[i]
+		if r.Action == rowInvalid {
+			continue
+		}
+		current, ok := existingByKey[scheduleKey(r.Schedule)]
+		if !ok {
+			r.Action = rowNew
+			continue
+		}
+		seen[current.ID] = true
+		r.Schedule.ID = current.ID
+		r.Changes = scheduleChanges(current, r.Schedule)
+		if len(r.Changes) == 0 {
+			r.Action = rowUnchanged
+		} else {
+			r.Action = rowChanged
+		}
+	}
+
+	var removed []models.Schedule
+	if replaceSemester {
+		for _, schedule := range existing {
+			if !seen[schedule.ID] {
+				removed = append(removed, schedule)
+			}
+		}
+	}
+	return removed, nil
+}
+
+// importSchedules upserts the valid rows by natural key. With replaceSemester, sessions of
+// the imported semesters that are not in the file are removed, so the file becomes the
+// whole timetable for those semesters. What it changed is saved as the history entry in
+// the same transaction. A non-nil check can refuse the plan before anything is written.
+func importSchedules(ctx context.Context, rows []scheduleRow, replaceSemester bool, progress progressFunc, history *models.ImportHistory, check planCheck) (scheduleImportStats, error) {
+	var stats scheduleImportStats
+	schedulesCollection := initialializers.DB.Collection("schedules")
+
+	err := importTransaction(ctx, func(ctx context.Context) error {
+		stats = scheduleImportStats{}
+
+		removed, err := planScheduleImport(ctx, rows, replaceSemester)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(scheduleImportRows(rows), scheduleIDs(removed)); err != nil {
+				return err
+			}
+		}
+
+		history.Created, history.Changed, history.Removed = nil, nil, nil
+		var touched []primitive.ObjectID
+		for _, r := range rows {
+			if r.Action == rowChanged {
+				touched = append(touched, r.Schedule.ID)
+			}
+		}
+		for _, schedule := range removed {
+			touched = append(touched, schedule.ID)
+		}
+		snapshots, err := snapshotDocuments(ctx, schedulesCollection, touched)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		writer := batchWriter{collection: schedulesCollection, progress: progress}
+		for i, r := range rows {
+			schedule := r.Schedule
+			switch r.Action {
+			case rowNew:
+				schedule.ID = primitive.NewObjectID()
+				schedule.CreatedAt = now
+				schedule.UpdatedAt = now
+				if err := writer.add(ctx, mongo.NewInsertOneModel().SetDocument(schedule), i+1); err != nil {
+					return err
+				}
+				history.Created = append(history.Created, schedule.ID)
+				stats.Inserted++
+			case rowUnchanged:
+				stats.Unchanged++
+			case rowChanged:
+				update := bson.M{"$set": bson.M{
+					"module_name":     schedule.ModuleName,
+					"end_time":        schedule.EndTime,
+					"end_minute":      schedule.EndMinute,
+					"room_name":       schedule.RoomName,
+					"instructor_name": schedule.InstructorName,
+					"updated_at":      now,
+				}}
+				if err := writer.add(ctx, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": schedule.ID}).SetUpdate(update), i+1); err != nil {
+					return err
+				}
+				history.Changed = append(history.Changed, models.ImportSnapshot{ID: schedule.ID, Fields: updateFields(update), Before: snapshots[schedule.ID]})
+				stats.Updated++
+			}
+		}
+
+		if err := writer.flush(ctx, len(rows)); err != nil {
+			return err
+		}
+
+		if len(removed) > 0 {
+			ids := make([]primitive.ObjectID, 0, len(removed))
+			for _, schedule := range removed {
+				ids = append(ids, schedule.ID)
+				history.Removed = append(history.Removed, models.ImportSnapshot{ID: schedule.ID, Before: snapshots[schedule.ID]})
+			}
+			result, err := schedulesCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+			if err != nil {
+				return err
+			}
+			stats.Removed = int(result.DeletedCount)
+		}
+		return saveImportHistory(ctx, history)
+	})
+	return stats, err
+}
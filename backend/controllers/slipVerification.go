@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"lateslip/slippdf"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errNoSlipSigningKey = errors.New("neither SLIP_SIGNING_SECRET nor JWT_SECRET is set, late slips cannot be signed")
+
+// slipSigningKey signs the verification links printed on late slips.
+// SLIP_SIGNING_SECRET falls back to JWT_SECRET; with neither set nothing is signed.
+func slipSigningKey() ([]byte, error) {
+	if secret := os.Getenv("SLIP_SIGNING_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	return nil, errNoSlipSigningKey
+}
+
+// slipSignature is the truncated HMAC-SHA256 of the slip ID
+func slipSignature(id primitive.ObjectID) (string, error) {
+	key, err := slipSigningKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("lateslip:" + id.Hex()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+func validSlipSignature(id primitive.ObjectID, signature string) (bool, error) {
+	expected, err := slipSignature(id)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(expected), []byte(signature)), nil
+}
+
+func slipVerificationURL(id primitive.ObjectID) (string, error) {
+	signature, err := slipSignature(id)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/lateslips/%s/verify?sig=%s", initialializers.PublicBaseURL, id.Hex(), signature), nil
+}
+
+// endOfCampusDay is midnight at the end of the campus day t falls on
+func endOfCampusDay(t time.Time) time.Time {
+	t = t.In(initialializers.CampusLocation)
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, initialializers.CampusLocation)
+}
+
+// slipValidUntil is when an approved slip stops being valid. Slips approved before the
+// expiry was stored are valid until the end of the day they were approved.
+func slipValidUntil(lateSlip models.LateSlip) time.Time {
+	if lateSlip.ValidUntil != nil {
+		return *lateSlip.ValidUntil
+	}
+	return endOfCampusDay(lateSlip.UpdatedAt)
+}
+
+// slipValidity reports whether the slip can still be shown, and why not
+func slipValidity(lateSlip models.LateSlip, now time.Time) (bool, string) {
+	if lateSlip.Status != "approved" {
+		return false, "Late slip is " + lateSlip.Status
+	}
+	if lateSlip.UsedAt != nil {
+		return false, "Late slip was already used"
+	}
+	if !now.Before(slipValidUntil(lateSlip)) {
+		return false, "Late slip has expired"
+	}
+	return true, ""
+}
+
+// GET /student/lateslips/:id/pdf and /admin/lateslips/:id/pdf
+func GetLateSlipPDF(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid late slip ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+	if c.GetString("role") == "student" {
+		// students can only print their own slips
+		userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid user ID"})
+			return
+		}
+		filter["student_id"] = userID
+	}
+
+	var lateSlip models.LateSlip
+	if err := initialializers.DB.Collection("lateslips").FindOne(ctx, filter).Decode(&lateSlip); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Late slip not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch late slip"})
+		return
+	}
+	if lateSlip.Status != "approved" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Only approved late slips can be printed"})
+		return
+	}
+	verifyURL, err := slipVerificationURL(lateSlip.ID)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Late slip signing is not configured"})
+		return
+	}
+
+	slip := slippdf.Slip{
+		ID:          lateSlip.ID.Hex(),
+		StudentName: lateSlip.StudentName,
+		RosterID:    lateSlip.RosterID,
+		Level:       lateSlip.Level,
+		Semester:    lateSlip.Semester,
+		ModuleCode:  lateSlip.ModuleCode,
+		ModuleName:  lateSlip.ModuleName,
+		Room:        lateSlip.RoomName,
+		Instructor:  lateSlip.InstructorName,
+		ClassStart:  lateSlip.ClassStartTime,
+		Reason:      lateSlip.Reason,
+		RequestedAt: formatExportTime(lateSlip.CreatedAt),
+		ApprovedAt:  formatExportTime(lateSlip.UpdatedAt),
+		ApprovedBy:  lateSlip.DecidedByName,
+		ValidUntil:  formatExportTime(slipValidUntil(lateSlip)),
+		VerifyURL:   verifyURL,
+	}
+	if lateSlip.DecidedAt != nil {
+		slip.ApprovedAt = formatExportTime(*lateSlip.DecidedAt)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="lateslip-%s.pdf"`, lateSlip.ID.Hex()))
+	c.Header("Content-Type", "application/pdf")
+	if err := slippdf.Render(c.Writer, slip); err != nil {
+		c.Error(err)
+	}
+}
+
+// GET /lateslips/:id/verify?sig=
+//
+// Public: whoever scans the QR code sees whether the slip is genuine and still valid.
+func VerifyLateSlip(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "valid": false, "error": "Late slip not found"})
+		return
+	}
+	signed, err := validSlipSignature(id, c.Query("sig"))
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Late slip verification is not configured"})
+		return
+	}
+	if !signed {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "valid": false, "error": "Late slip not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var lateSlip models.LateSlip
+	if err := initialializers.DB.Collection("lateslips").FindOne(ctx, bson.M{"_id": id}).Decode(&lateSlip); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "valid": false, "error": "Late slip not found"})
+			return
+		}
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to fetch late slip"})
+		return
+	}
+
+	valid, reason := slipValidity(lateSlip, time.Now())
+	response := gin.H{
+		"success": true,
+		"valid":   valid,
+		"lateSlip": gin.H{
+			"id":          lateSlip.ID.Hex(),
+			"status":      lateSlip.Status,
+			"studentName": lateSlip.StudentName,
+			"rosterId":    lateSlip.RosterID,
+			"moduleCode":  lateSlip.ModuleCode,
+			"moduleName":  lateSlip.ModuleName,
+			"room":        lateSlip.RoomName,
+			"classStart":  lateSlip.ClassStartTime,
+			"approvedBy":  lateSlip.DecidedByName,
+			"validUntil":  slipValidUntil(lateSlip),
+			"usedAt":      lateSlip.UsedAt,
+		},
+	}
+	if !valid {
+		response["reason"] = reason
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"context"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// studentRow is a parsed roster row
+type studentRow struct {
+	importRow `bson:",inline"`
+	Student   models.Student `json:"student" bson:"student"`
+}
+
+// parseStudentRows maps the header and turns every following row into a Student.
+// Row problems are recorded on the row; only header problems are returned as an error.
+func parseStudentRows(rows [][]string) (columnMapping, []studentRow, error) {
+	columns, err := mapHeader(rows[0], studentColumns())
+	if err != nil {
+		return columns, nil, err
+	}
+
+	validator := validator.New()
+	var parsed []studentRow
+	for i, row := range rows[1:] { // Skip header row
+		student := models.Student{
+			StudentID:     columns.Get(row, "student_id"),
+			Name:          columns.Get(row, "name"),
+			Email:         models.NormalizeEmail(columns.Get(row, "email")),
+			Semester:      columns.Get(row, "semester"),
+			Level:         columns.Get(row, "level"),
+			LateSlipCount: 0, // Default value
+		}
+		if student.StudentID == "" && student.Name == "" && student.Email == "" {
+			continue // blank line
+		}
+
+		r := studentRow{importRow: importRow{Row: i + 2, Cells: row}, Student: student}
+		for _, column := range studentColumns() {
+			if column.Required && columns.Get(row, column.Field) == "" {
+				r.invalid("missing "+column.Field, columns.Column(column.Field))
+			}
+		}
+		if student.Email != "" && validator.Var(student.Email, "email") != nil {
+			r.invalid("invalid email address", columns.Column("email"))
+		}
+		if err := validator.Struct(student); err != nil {
+			r.invalid(err.Error())
+		}
+		parsed = append(parsed, r)
+	}
+	return columns, parsed, nil
+}
+
+// studentImportStats counts what an import did to the roster
+type studentImportStats struct {
+	New         int `json:"new"`
+	Updated     int `json:"updated"`
+	Unchanged   int `json:"unchanged"`
+	Skipped     int `json:"skipped"` // invalid rows
+	Deactivated int `json:"deactivated"`
+}
+
+// planStudentImport matches each valid row to a roster record by student ID, then by
+// email, and sets its action. With deactivateMissing it also returns the active students
+// the file no longer contains.
+func planStudentImport(ctx context.Context, rows []studentRow, columns columnMapping, deactivateMissing bool) ([]models.Student, error) {
+	cursor, err := initialializers.DB.Collection("students").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var existing []models.Student
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+	byStudentID := make(map[string]models.Student, len(existing))
+	byEmail := make(map[string]models.Student, len(existing))
+	for _, student := range existing {
+		if student.StudentID != "" {
+			byStudentID[strings.ToLower(student.StudentID)] = student
+		}
+		byEmail[strings.ToLower(student.Email)] = student
+	}
+
+	seenEmails := map[string]int{}
+	seenIDs := map[string]int{}
+	matched := map[primitive.ObjectID]bool{}
+	for i := range rows {
+		r := &rows[i]
+		if r.Action == rowInvalid {
+			continue
+		}
+		email := strings.ToLower(r.Student.Email)
+		studentID := strings.ToLower(r.Student.StudentID)
+		if previous, exists := seenIDs[studentID]; exists {
+			r.invalid("duplicate student ID, already given in row "+strconv.Itoa(previous), columns.Column("student_id"))
+			continue
+		}
+		if previous, exists := seenEmails[email]; exists {
+			r.invalid("duplicate email, already given in row "+strconv.Itoa(previous), columns.Column("email"))
+			continue
+		}
+		seenIDs[studentID] = r.Row
+		seenEmails[email] = r.Row
+
+		byID, idExists := byStudentID[studentID]
+		byMail, emailExists := byEmail[email]
+		if idExists && emailExists && byID.ID != byMail.ID {
+			r.invalid("student ID and email belong to different students", columns.Column("student_id"), columns.Column("email"))
+			continue
+		}
+
+		current := byID
+		if !idExists {
+			current = byMail
+		}
+		if !idExists && !emailExists {
+			r.Action = rowNew
+			continue
+		}
+		matched[current.ID] = true
+		r.Student.ID = current.ID
+		r.Changes = studentChanges(current, r.Student)
+		if len(r.Changes) == 0 {
+			r.Action = rowUnchanged
+		} else {
+			r.Action = rowChanged
+		}
+	}
+
+	var missing []models.Student
+	if deactivateMissing {
+		for _, student := range existing {
+			if !matched[student.ID] && !student.Inactive {
+				missing = append(missing, student)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// studentChanges lists the roster fields that differ between the stored and imported student
+func studentChanges(current, imported models.Student) []string {
+	var changes []string
+	if current.StudentID != imported.StudentID {
+		changes = append(changes, "student_id")
+	}
+	if current.Name != imported.Name {
+		changes = append(changes, "name")
+	}
+	if !strings.EqualFold(current.Email, imported.Email) {
+		changes = append(changes, "email")
+	}
+	if current.Semester != imported.Semester {
+		changes = append(changes, "semester")
+	}
+	if current.Level != imported.Level {
+		changes = append(changes, "level")
+	}
+	if current.Inactive {
+		changes = append(changes, "inactive")
+	}
+	return changes
+}
+
+// importStudents inserts new students, updates changed ones and, with deactivateMissing,
+// marks students that are not in the file as inactive. What it changed is saved as the
+// history entry in the same transaction. A non-nil check can refuse the plan before
+// anything is written.
+func importStudents(ctx context.Context, rows []studentRow, columns columnMapping, deactivateMissing bool, progress progressFunc, history *models.ImportHistory, check planCheck) (studentImportStats, error) {
+	var stats studentImportStats
+	studentsCollection := initialializers.DB.Collection("students")
+
+	err := importTransaction(ctx, func(ctx context.Context) error {
+		stats = studentImportStats{}
+
+		missing, err := planStudentImport(ctx, rows, columns, deactivateMissing)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(studentImportRows(rows), studentIDs(missing)); err != nil {
+				return err
+			}
+		}
+
+		history.Created, history.Changed, history.Removed = nil, nil, nil
+		var touched []primitive.ObjectID
+		for _, r := range rows {
+			if r.Action == rowChanged {
+				touched = append(touched, r.Student.ID)
+			}
+		}
+		for _, student := range missing {
+			touched = append(touched, student.ID)
+		}
+		snapshots, err := snapshotDocuments(ctx, studentsCollection, touched)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		writer := batchWriter{collection: studentsCollection, progress: progress}
+		for i, r := range rows {
+			student := r.Student
+			switch r.Action {
+			case rowNew:
+				student.ID = primitive.NewObjectID()
+				if err := writer.add(ctx, mongo.NewInsertOneModel().SetDocument(student), i+1); err != nil {
+					return err
+				}
+				history.Created = append(history.Created, student.ID)
+				stats.New++
+			case rowUnchanged:
+				stats.Unchanged++
+			case rowChanged:
+				set := bson.M{
+					"student_id": student.StudentID,
+					"name":       student.Name,
+					"email":      student.Email,
+					"semester":   student.Semester,
+					"level":      student.Level,
+				}
+				// the late slip quota is per semester, so a new semester starts from zero; requests
+				// still pending from the old one no longer hold a place
+				for _, change := range r.Changes {
+					if change == "semester" {
+						set["late_slip_count"] = 0
+						set["pending_slip_count"] = 0
+					}
+				}
+				update := bson.M{"$set": set, "$unset": bson.M{"inactive": "", "deactivated_at": ""}}
+				if err := writer.add(ctx, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": student.ID}).SetUpdate(update), i+1); err != nil {
+					return err
+				}
+				history.Changed = append(history.Changed, models.ImportSnapshot{ID: student.ID, Fields: updateFields(update), Before: snapshots[student.ID]})
+				stats.Updated++
+			case rowInvalid:
+				stats.Skipped++
+			}
+		}
+
+		if err := writer.flush(ctx, len(rows)); err != nil {
+			return err
+		}
+
+		if len(missing) > 0 {
+			ids := make([]primitive.ObjectID, 0, len(missing))
+			for _, student := range missing {
+				ids = append(ids, student.ID)
+				history.Changed = append(history.Changed, models.ImportSnapshot{ID: student.ID, Fields: []string{"inactive", "deactivated_at"}, Before: snapshots[student.ID]})
+			}
+			result, err := studentsCollection.UpdateMany(
+				ctx,
+				bson.M{"_id": bson.M{"$in": ids}},
+				bson.M{"$set": bson.M{"inactive": true, "deactivated_at": now}},
+			)
+			if err != nil {
+				return err
+			}
+			stats.Deactivated = int(result.ModifiedCount)
+		}
+		return saveImportHistory(ctx, history)
+	})
+	return stats, err
+}
@@ -1,15 +1,14 @@
 package controllers
 
 import (
+	"context"
 	"lateslip/initialializers"
 	"lateslip/models"
-	"log"
+	"lateslip/sessions"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -36,6 +35,7 @@ func Register(ctx *gin.Context) {
 	}
 	//TODO: need to validate the email address format as the student model might not be correctly set up
 	// student model will be updated later to include more fields
+	b.Email = models.NormalizeEmail(b.Email)
 
 	// Check if email exists in student database
 	studentCollection := initialializers.DB.Collection("students")
@@ -55,11 +55,32 @@ func Register(ctx *gin.Context) {
 		}
 		return
 	}
+	if student.Inactive {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "This student is no longer on the roster",
+		})
+		return
+	}
 
-	// Check if user already exists in users collection
+	// Check if user already exists in users collection. An account nobody has verified
+	// yet keeps its password: registering it again only sends a new verification email
+	// to the address, so nobody can take the account over before its owner confirms it.
 	userCollection := initialializers.DB.Collection("users")
-	var user models.User
-	err = userCollection.FindOne(ctx, bson.M{"email": b.Email}).Decode(&user)
+	var existing models.User
+	err = userCollection.FindOne(ctx, bson.M{"email": b.Email}).Decode(&existing)
+	if err == nil && existing.PendingVerification {
+		if err := resendRegistrationEmail(ctx, existing); err != nil {
+			ctx.Error(err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "Error creating user",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, registeredResponse(existing, student))
+		return
+	}
 	if err == nil {
 		ctx.JSON(http.StatusConflict, gin.H{
 			"success": false,
@@ -67,6 +88,13 @@ func Register(ctx *gin.Context) {
 		})
 		return
 	}
+	if err != mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Database error",
+		})
+		return
+	}
 
 	//hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(b.Password), 10)
@@ -78,37 +106,72 @@ func Register(ctx *gin.Context) {
 		return
 	}
 
-	//create user
-	user = models.User{
-		ID:        primitive.NewObjectID(),
-		Fullname:  b.Fullname,
-		Email:     b.Email,
-		Password:  string(hashedPassword),
-		Role:      "student",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	//create user, pending until the email is verified
+	user := models.User{
+		ID:                  primitive.NewObjectID(),
+		Fullname:            b.Fullname,
+		Email:               b.Email,
+		Password:            string(hashedPassword),
+		Role:                "student",
+		StudentID:           student.ID,
+		PendingVerification: true,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
 	}
 
-	_, err = userCollection.InsertOne(ctx, user)
+	err = initialializers.WithTransaction(ctx.Request.Context(), func(txCtx context.Context) error {
+		if _, err := userCollection.InsertOne(txCtx, user); err != nil {
+			return err
+		}
+		return sendEmailVerification(txCtx, user)
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		status, message := registerStatus(err)
+		if status == http.StatusInternalServerError {
+			ctx.Error(err)
+		}
+		ctx.JSON(status, gin.H{
 			"success": false,
-			"error":   "Error creating user",
+			"error":   message,
 		})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
+	ctx.JSON(http.StatusOK, registeredResponse(user, student))
+}
+
+// registeredResponse answers a registration, whether it created the account or the
+// account was already waiting for verification
+func registeredResponse(user models.User, student models.Student) gin.H {
+	return gin.H{
 		"success": true,
-		"message": "User created",
+		"message": "User created, check your email to verify your account",
 		"user": gin.H{
-			"id":        user.ID.Hex(),
-			"fullname":  user.Fullname,
-			"email":     user.Email,
-			"role":      user.Role,
-			"createdAt": user.CreatedAt,
-			"updatedAt": user.UpdatedAt,
+			"id":                  user.ID.Hex(),
+			"fullname":            user.Fullname,
+			"email":               user.Email,
+			"role":                user.Role,
+			"studentId":           student.StudentID,
+			"semester":            student.Semester,
+			"level":               student.Level,
+			"pendingVerification": user.PendingVerification,
+			"createdAt":           user.CreatedAt,
+			"updatedAt":           user.UpdatedAt,
 		},
+	}
+}
+
+// resendRegistrationEmail sends a new verification email for an account registered
+// again before it was verified. It counts like a resend, and while the address or IP
+// is throttled nothing is sent, so the answer stays the same.
+func resendRegistrationEmail(c *gin.Context, user models.User) error {
+	wait, _, err := initialializers.VerificationGuard.Check(c.Request.Context(), user.Email, c.ClientIP(), time.Now())
+	if err != nil || wait > 0 {
+		return err
+	}
+	countVerificationAttempt(c, user.Email)
+	return initialializers.WithTransaction(c.Request.Context(), func(txCtx context.Context) error {
+		return sendEmailVerification(txCtx, user)
 	})
 }
 
@@ -130,12 +193,20 @@ func Login(ctx *gin.Context) {
 		return
 	}
 
+	b.Email = models.NormalizeEmail(b.Email)
+
+	// Refuse while the account or IP is throttled after failed attempts
+	if refuseThrottledLogin(ctx, b.Email) {
+		return
+	}
+
 	// Check if user exists
 	userCollection := initialializers.DB.Collection("users")
 	var user models.User
 	err = userCollection.FindOne(ctx, bson.M{"email": b.Email}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
+			loginFailed(ctx, b.Email, nil, loginUnknownUser)
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"error":   "Invalid email or password",
@@ -152,6 +223,7 @@ func Login(ctx *gin.Context) {
 	// Check if password is correct
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(b.Password))
 	if err != nil {
+		loginFailed(ctx, b.Email, &user, loginWrongPassword)
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"error":   "Invalid email or password",
@@ -159,16 +231,41 @@ func Login(ctx *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID.Hex(),
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24-hour expiration
-	})
+	// Students sign in at /student/login and instructors at /instructor/login
+	if portal := loginPortal(ctx); user.Role != portal {
+		recordLogin(ctx, b.Email, &user, false, loginWrongPortal)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "This login is for " + portal + "s",
+		})
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	// Disabled accounts cannot start a session
+	if user.Disabled {
+		recordLogin(ctx, b.Email, &user, false, loginDisabled)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "This account has been disabled",
+		})
+		return
+	}
+
+	// Neither can accounts whose email was never confirmed
+	if user.PendingVerification {
+		recordLogin(ctx, b.Email, &user, false, loginUnverified)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success":              false,
+			"error":                "Please verify your email address before logging in",
+			"verificationRequired": true,
+		})
+		return
+	}
+
+	// Start a session with a short-lived access token and a refresh token
+	tokens, err := sessions.Start(ctx.Request.Context(), user, sessionClient(ctx))
 	if err != nil {
-		log.Fatal("Failed to sign token:", err)
+		ctx.Error(err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to generate token",
@@ -176,20 +273,21 @@ func Login(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Login successful",
-		"token":   tokenString,
-	})
+	loginSucceeded(ctx, b.Email, &user)
+	ctx.JSON(http.StatusOK, tokenResponse("Login successful", tokens))
 }
 
 // AdminRegister handler
+//
+// Requires an invite token from an existing admin, or ADMIN_BOOTSTRAP_TOKEN for the
+// first admin of a fresh installation.
 func AdminRegister(ctx *gin.Context) {
-	//get username , email , password from request body
+	//get username , email , password and invite token from request body
 	type body struct {
-		Fullname string `json:"fullname"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Fullname    string `json:"fullname" binding:"required"`
+		Email       string `json:"email" binding:"required,email"`
+		Password    string `json:"password" binding:"required,min=8"`
+		InviteToken string `json:"inviteToken" binding:"required"`
 	}
 
 	var b body
@@ -202,18 +300,6 @@ func AdminRegister(ctx *gin.Context) {
 		return
 	}
 
-	//check if user already exists
-	userCollection := initialializers.DB.Collection("users")
-	var user models.User
-	err = userCollection.FindOne(ctx, bson.M{"email": b.Email}).Decode(&user)
-	if err == nil {
-		ctx.JSON(http.StatusConflict, gin.H{
-			"success": false,
-			"error":   "User already exists",
-		})
-		return
-	}
-
 	//hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(b.Password), 10)
 	if err != nil {
@@ -224,21 +310,26 @@ func AdminRegister(ctx *gin.Context) {
 		return
 	}
 	//create user
-	user = models.User{
+	user := models.User{
 		ID:        primitive.NewObjectID(),
 		Fullname:  b.Fullname,
-		Email:     b.Email,
+		Email:     models.NormalizeEmail(b.Email),
 		Password:  string(hashedPassword),
 		Role:      "admin",
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	_, err = userCollection.InsertOne(ctx, user)
+	//consume the invite and insert the user together
+	err = registerAdmin(ctx.Request.Context(), user, b.InviteToken)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		status, message := registerStatus(err)
+		if status == http.StatusInternalServerError {
+			ctx.Error(err)
+		}
+		ctx.JSON(status, gin.H{
 			"success": false,
-			"error":   "Error creating user",
+			"error":   message,
 		})
 		return
 	}
@@ -275,12 +366,20 @@ func AdminLogin(ctx *gin.Context) {
 		return
 	}
 
+	b.Email = models.NormalizeEmail(b.Email)
+
+	//refuse while the account or IP is throttled after failed attempts
+	if refuseThrottledLogin(ctx, b.Email) {
+		return
+	}
+
 	//check if user exists
 	userCollection := initialializers.DB.Collection("users")
 	var user models.User
 	err = userCollection.FindOne(ctx, bson.M{"email": b.Email}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
+			loginFailed(ctx, b.Email, nil, loginUnknownUser)
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"error":   "Invalid email or password",
@@ -297,6 +396,7 @@ func AdminLogin(ctx *gin.Context) {
 	//check if password is correct
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(b.Password))
 	if err != nil {
+		loginFailed(ctx, b.Email, &user, loginWrongPassword)
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"error":   "Invalid email or password",
@@ -304,15 +404,41 @@ func AdminLogin(ctx *gin.Context) {
 		return
 	}
 
-	//generate jwt token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID.Hex(),
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24-hour expiration
-	})
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	//only administrators sign in here
+	if user.Role != "admin" {
+		recordLogin(ctx, b.Email, &user, false, loginWrongPortal)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "This login is for administrators",
+		})
+		return
+	}
+
+	//disabled accounts cannot start a session
+	if user.Disabled {
+		recordLogin(ctx, b.Email, &user, false, loginDisabled)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "This account has been disabled",
+		})
+		return
+	}
+
+	//neither can accounts whose email was never confirmed
+	if user.PendingVerification {
+		recordLogin(ctx, b.Email, &user, false, loginUnverified)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"success":              false,
+			"error":                "Please verify your email address before logging in",
+			"verificationRequired": true,
+		})
+		return
+	}
+
+	//start a session
+	tokens, err := sessions.Start(ctx.Request.Context(), user, sessionClient(ctx))
 	if err != nil {
-		log.Fatal("Failed to sign token:", err)
+		ctx.Error(err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to generate token",
@@ -320,10 +446,8 @@ func AdminLogin(ctx *gin.Context) {
 		return
 	}
 
-	//return token
-	ctx.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Login successful",
-		"token":   tokenString,
-	})
+	loginSucceeded(ctx, b.Email, &user)
+
+	//return tokens
+	ctx.JSON(http.StatusOK, tokenResponse("Login successful", tokens))
 }
@@ -5,15 +5,17 @@ import (
 )
 
 type ClientManager struct {
-	adminClients   map[*Client]bool
-	studentClients map[string]*Client
-	mu             sync.Mutex
+	adminClients      map[*Client]bool
+	studentClients    map[string]*Client
+	instructorClients map[*Client]bool
+	mu                sync.Mutex
 }
 
 func NewClientManager() *ClientManager {
 	return &ClientManager{
-		adminClients:   make(map[*Client]bool),
-		studentClients: make(map[string]*Client),
+		adminClients:      make(map[*Client]bool),
+		studentClients:    make(map[string]*Client),
+		instructorClients: make(map[*Client]bool),
 	}
 }
 
@@ -24,6 +26,8 @@ func (cm *ClientManager) Register(client *Client) {
 
 	if client.IsAdmin {
 		cm.adminClients[client] = true
+	} else if client.InstructorName != "" {
+		cm.instructorClients[client] = true
 	} else {
 		cm.studentClients[client.UserID] = client
 	}
@@ -43,6 +47,11 @@ func (cm *ClientManager) Unregister(client *Client) {
 			delete(cm.adminClients, client)
 			close(client.Send)
 		}
+	} else if client.InstructorName != "" {
+		if _, ok := cm.instructorClients[client]; ok {
+			delete(cm.instructorClients, client)
+			close(client.Send)
+		}
 	} else {
 		if existingClient, ok := cm.studentClients[client.UserID]; ok && existingClient == client {
 			delete(cm.studentClients, client.UserID)
@@ -9,7 +9,8 @@ import (
 
 var clientManager = NewClientManager()
 
-// WebSocketHandler handles WebSocket connections for both admin and student clients
+// WebSocketHandler handles WebSocket connections for admin, student and instructor clients.
+// Instructor connections need instructor_name set in the context.
 func WebSocketHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
 	role := c.GetString("role")
@@ -30,6 +31,9 @@ func WebSocketHandler(c *gin.Context) {
 
 	// Create new client
 	client := NewClient(conn, userID, isAdmin, clientManager)
+	if role == "instructor" {
+		client.InstructorName = c.GetString("instructor_name")
+	}
 
 	// Register client with manager
 	clientManager.Register(client)
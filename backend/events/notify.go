@@ -3,18 +3,26 @@ package events
 import (
 	"encoding/json"
 	"lateslip/models"
+	"strings"
 )
 
+// NotifyStudent sends a message to the student's connection. The message is dropped
+// when the client's send buffer is full.
 func NotifyStudent(studentID string, message string) {
 	clientManager.mu.Lock()
 	defer clientManager.mu.Unlock()
 
 	if client, exists := clientManager.studentClients[studentID]; exists {
 		// If message is already JSON, use it directly
-		client.Send <- []byte(message)
+		select {
+		case client.Send <- []byte(message):
+		default:
+		}
 	}
 }
 
+// NotifyAdmins tells every connected admin about a new late slip request. Admins whose
+// send buffer is full miss the event.
 func NotifyAdmins(message string, lateSlip models.LateSlip) {
 	msg := map[string]interface{}{
 		"type":    "NEW_LATE_SLIP_REQUEST",
@@ -22,6 +30,12 @@ func NotifyAdmins(message string, lateSlip models.LateSlip) {
 		"data": map[string]interface{}{
 			"id":        lateSlip.ID.Hex(),
 			"studentId": lateSlip.StudentID.Hex(),
+			"rosterId":  lateSlip.RosterID,
+			"name":      lateSlip.StudentName,
+			"level":     lateSlip.Level,
+			"semester":  lateSlip.Semester,
+			"module":    lateSlip.ModuleCode,
+			"room":      lateSlip.RoomName,
 			"reason":    lateSlip.Reason,
 			"status":    lateSlip.Status,
 			"createdAt": lateSlip.CreatedAt,
@@ -37,6 +51,88 @@ func NotifyAdmins(message string, lateSlip models.LateSlip) {
 	defer clientManager.mu.Unlock()
 
 	for client := range clientManager.adminClients {
-		client.Send <- jsonMsg
+		select {
+		case client.Send <- jsonMsg:
+		default:
+		}
 	}
-}
\ No newline at end of file
+}
+
+// NotifyImportJob sends the progress of an import job to the admin who started it.
+// Updates are dropped for a client whose send buffer is full.
+func NotifyImportJob(job models.ImportJob) {
+	msg := map[string]interface{}{
+		"type": "IMPORT_PROGRESS",
+		"data": map[string]interface{}{
+			"id":        job.ID.Hex(),
+			"kind":      job.Kind,
+			"status":    job.Status,
+			"stage":     job.Stage,
+			"total":     job.Total,
+			"processed": job.Processed,
+			"invalid":   job.Invalid,
+			"error":     job.Error,
+		},
+	}
+
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	clientManager.mu.Lock()
+	defer clientManager.mu.Unlock()
+
+	for client := range clientManager.adminClients {
+		if client.UserID != job.CreatedBy {
+			continue
+		}
+		select {
+		case client.Send <- jsonMsg:
+		default:
+		}
+	}
+}
+
+// NotifyInstructors sends a late slip event to the instructor of the slip's session,
+// e.g. LATE_SLIP_APPROVED when a student on their way in gets a slip. Events are dropped
+// for a client whose send buffer is full.
+func NotifyInstructors(eventType string, lateSlip models.LateSlip) {
+	if lateSlip.InstructorName == "" {
+		return
+	}
+
+	msg := map[string]interface{}{
+		"type": eventType,
+		"data": map[string]interface{}{
+			"id":         lateSlip.ID.Hex(),
+			"rosterId":   lateSlip.RosterID,
+			"name":       lateSlip.StudentName,
+			"module":     lateSlip.ModuleCode,
+			"moduleName": lateSlip.ModuleName,
+			"room":       lateSlip.RoomName,
+			"classStart": lateSlip.ClassStartTime,
+			"status":     lateSlip.Status,
+			"validUntil": lateSlip.ValidUntil,
+			"usedAt":     lateSlip.UsedAt,
+		},
+	}
+
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	clientManager.mu.Lock()
+	defer clientManager.mu.Unlock()
+
+	for client := range clientManager.instructorClients {
+		if !strings.EqualFold(strings.TrimSpace(client.InstructorName), strings.TrimSpace(lateSlip.InstructorName)) {
+			continue
+		}
+		select {
+		case client.Send <- jsonMsg:
+		default:
+		}
+	}
+}
@@ -23,6 +23,8 @@ type Client struct {
 	Conn     *websocket.Conn
 	UserID   string
 	IsAdmin  bool
+	// InstructorName is set for instructors, whose sessions are matched by name
+	InstructorName string
 	Send     chan []byte
 	Manager  *ClientManager
 }
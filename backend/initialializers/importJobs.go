@@ -0,0 +1,35 @@
+package initialializers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FailInterruptedImportJobs marks import jobs that were queued or running when the server
+// stopped as failed, so their uploaders do not wait for them forever.
+func FailInterruptedImportJobs() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := DB.Collection("import_jobs").UpdateMany(
+		ctx,
+		bson.M{"status": bson.M{"$in": []string{"queued", "running"}}},
+		bson.M{"$set": bson.M{
+			"status":      "failed",
+			"error":       "Interrupted by a server restart, please upload the file again",
+			"finished_at": now,
+			"updated_at":  now,
+		}},
+	)
+	if err != nil {
+		log.Printf("Failed to clean up interrupted import jobs: %v", err)
+		return
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Marked %d interrupted import jobs as failed", result.ModifiedCount)
+	}
+}
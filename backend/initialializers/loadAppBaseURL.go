@@ -0,0 +1,42 @@
+package initialializers
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// AppBaseURL is where the web app is served, e.g. "https://lateslip.example.edu". Links
+// in emails are built from it and never from the request, whose Host header the sender
+// controls.
+var AppBaseURL string
+
+// PublicBaseURL is where this API is reachable from outside, e.g.
+// "https://api.lateslip.example.edu". The QR codes on printed late slips point to its
+// verify endpoint, for the same reason never to the request's host.
+var PublicBaseURL string
+
+// LoadAppBaseURL reads APP_BASE_URL, which is required
+func LoadAppBaseURL() {
+	AppBaseURL = requiredBaseURL("APP_BASE_URL", "the links in invite, password reset and verification emails")
+}
+
+// LoadPublicBaseURL reads PUBLIC_BASE_URL, which is required
+func LoadPublicBaseURL() {
+	PublicBaseURL = requiredBaseURL("PUBLIC_BASE_URL", "the QR codes on printed late slips")
+}
+
+// requiredBaseURL reads an absolute http(s) URL from the environment, without a
+// trailing slash, and stops the server when it is missing or malformed
+func requiredBaseURL(name, neededFor string) string {
+	value := strings.TrimRight(strings.TrimSpace(os.Getenv(name)), "/")
+	if value == "" {
+		log.Fatalf("%s is not set; it is needed for %s", name, neededFor)
+	}
+	base, err := url.Parse(value)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		log.Fatalf("Invalid %s %q, expected an absolute http(s) URL", name, value)
+	}
+	return value
+}
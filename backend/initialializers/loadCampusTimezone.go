@@ -0,0 +1,29 @@
+package initialializers
+
+import (
+	"log"
+	"os"
+	"time"
+	_ "time/tzdata" // make CAMPUS_TIMEZONE work on hosts without zoneinfo
+)
+
+// CampusLocation is the timezone class times in the schedule are given in
+var CampusLocation = time.Local
+
+// LoadCampusTimezone reads CAMPUS_TIMEZONE, e.g. "Asia/Kathmandu"
+func LoadCampusTimezone() {
+	name := os.Getenv("CAMPUS_TIMEZONE")
+	if name == "" {
+		return
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatalf("Invalid CAMPUS_TIMEZONE %q: %v", name, err)
+	}
+	CampusLocation = location
+}
+
+// CampusNow returns the current time on campus
+func CampusNow() time.Time {
+	return time.Now().In(CampusLocation)
+}
@@ -0,0 +1,14 @@
+package initialializers
+
+import (
+	"lateslip/mailtemplates"
+	"log"
+	"os"
+)
+
+// LoadEmailTemplates parses the email templates, using overrides from EMAIL_TEMPLATE_DIR when set
+func LoadEmailTemplates() {
+	if err := mailtemplates.Load(os.Getenv("EMAIL_TEMPLATE_DIR")); err != nil {
+		log.Fatal("Failed to load email templates: ", err)
+	}
+}
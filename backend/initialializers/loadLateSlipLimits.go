@@ -0,0 +1,71 @@
+package initialializers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultLateSlipLimit is used when no limit is configured for a level
+const DefaultLateSlipLimit = 4
+
+var lateSlipLimits = map[string]int{}
+var defaultLateSlipLimit = DefaultLateSlipLimit
+var quotaWarningThreshold = 1
+
+// LoadLateSlipLimits reads the per-semester late slip quota from the environment.
+//
+//	LATE_SLIP_LIMIT_DEFAULT=4
+//	LATE_SLIP_LIMITS=Level 4:4,Level 5:4,Level 6:3
+//	LATE_SLIP_WARNING_THRESHOLD=1
+func LoadLateSlipLimits() {
+	if value := strings.TrimSpace(os.Getenv("LATE_SLIP_WARNING_THRESHOLD")); value != "" {
+		threshold, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid LATE_SLIP_WARNING_THRESHOLD: %q", value)
+		}
+		quotaWarningThreshold = threshold
+	}
+
+	if value := strings.TrimSpace(os.Getenv("LATE_SLIP_LIMIT_DEFAULT")); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			log.Fatalf("Invalid LATE_SLIP_LIMIT_DEFAULT: %q", value)
+		}
+		defaultLateSlipLimit = limit
+	}
+
+	for _, entry := range strings.Split(os.Getenv("LATE_SLIP_LIMITS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 {
+			log.Fatalf("Invalid LATE_SLIP_LIMITS entry: %q", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(entry[idx+1:]))
+		if err != nil || limit < 0 {
+			log.Fatalf("Invalid LATE_SLIP_LIMITS entry: %q", entry)
+		}
+		lateSlipLimits[normalizeLevel(entry[:idx])] = limit
+	}
+}
+
+// LateSlipLimit returns the number of late slips a student of the given level may use per semester
+func LateSlipLimit(level string) int {
+	if limit, ok := lateSlipLimits[normalizeLevel(level)]; ok {
+		return limit
+	}
+	return defaultLateSlipLimit
+}
+
+// QuotaWarningThreshold is the number of remaining late slips at or below which the student is warned
+func QuotaWarningThreshold() int {
+	return quotaWarningThreshold
+}
+
+func normalizeLevel(level string) string {
+	return strings.ToLower(strings.TrimSpace(level))
+}
@@ -0,0 +1,26 @@
+package initialializers
+
+import (
+	"lateslip/loginguard"
+	"log"
+)
+
+var LoginGuard *loginguard.Guard
+
+// VerificationGuard throttles email verification codes and resent verification emails
+var VerificationGuard *loginguard.Guard
+
+// LoadLoginGuard sets up failed-login and verification throttling; call after ConnectToDB
+func LoadLoginGuard() {
+	guard, err := loginguard.FromEnv(DB, "", loginguard.DefaultPolicy)
+	if err != nil {
+		log.Fatal("Failed to configure login guard: ", err)
+	}
+	LoginGuard = guard
+
+	guard, err = loginguard.FromEnv(DB, "verify:", loginguard.VerificationPolicy)
+	if err != nil {
+		log.Fatal("Failed to configure verification guard: ", err)
+	}
+	VerificationGuard = guard
+}
@@ -0,0 +1,17 @@
+package initialializers
+
+import (
+	"lateslip/notifier"
+	"log"
+)
+
+var Notifier notifier.Notifier
+
+// LoadNotifier picks the email backend from the environment
+func LoadNotifier() {
+	n, err := notifier.FromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure notifier: ", err)
+	}
+	Notifier = n
+}
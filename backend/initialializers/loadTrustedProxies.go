@@ -0,0 +1,21 @@
+package initialializers
+
+import (
+	"os"
+	"strings"
+)
+
+// TrustedProxies are the proxies whose X-Forwarded-For header gin believes when it works
+// out the client IP. Nil, the default, trusts no one so the IP is the connection's peer,
+// which keeps the login throttling from being sidestepped with a forged header.
+var TrustedProxies []string
+
+// LoadTrustedProxies reads TRUSTED_PROXIES, a comma separated list of IPs or CIDRs,
+// e.g. "10.0.0.0/8,127.0.0.1". The router rejects entries that do not parse.
+func LoadTrustedProxies() {
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			TrustedProxies = append(TrustedProxies, proxy)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package initialializers
+
+import (
+	"context"
+	"lateslip/models"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrateEmails rewrites user and roster emails stored before they were normalised, as
+// logins, registration and the roster import now look them up by models.NormalizeEmail.
+// An address that would clash with another document is logged and left alone.
+func MigrateEmails() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, name := range []string{"users", "students"} {
+		collection := DB.Collection(name)
+		filter := bson.M{"$expr": bson.M{"$ne": bson.A{"$email", bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}}}}
+		cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"email": 1}))
+		if err != nil {
+			log.Printf("Failed to look up %s emails to normalise: %v", name, err)
+			continue
+		}
+
+		migrated := 0
+		for cursor.Next(ctx) {
+			var doc struct {
+				ID    primitive.ObjectID `bson:"_id"`
+				Email string             `bson:"email"`
+			}
+			if err := cursor.Decode(&doc); err != nil {
+				continue
+			}
+			email := models.NormalizeEmail(doc.Email)
+			clashes, err := collection.CountDocuments(ctx, bson.M{"email": email, "_id": bson.M{"$ne": doc.ID}})
+			if err == nil && clashes > 0 {
+				log.Printf("Email %q of %s %s is already used by another record in a different case; fix it by hand", doc.Email, name, doc.ID.Hex())
+				continue
+			}
+			if err == nil {
+				_, err = collection.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"email": email}})
+			}
+			if err != nil {
+				log.Printf("Email of %s %s could not be normalised: %v", name, doc.ID.Hex(), err)
+				continue
+			}
+			migrated++
+		}
+		cursor.Close(ctx)
+		if migrated > 0 {
+			log.Printf("Normalised the email of %d %s", migrated, name)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package initialializers
+
+import (
+	"context"
+	"lateslip/models"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MigratePendingSlipCounts fills pending_slip_count for students stored before requests
+// held a place in the quota, from their pending requests of the current semester
+func MigratePendingSlipCounts() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	students := DB.Collection("students")
+	cursor, err := students.Find(ctx, bson.M{"pending_slip_count": bson.M{"$exists": false}})
+	if err != nil {
+		log.Printf("Failed to look up students to migrate: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var student models.Student
+		if err := cursor.Decode(&student); err != nil {
+			continue
+		}
+		pending, err := DB.Collection("lateslips").CountDocuments(ctx, bson.M{"student_record_id": student.ID, "status": "pending", "semester": student.Semester})
+		if err == nil {
+			_, err = students.UpdateOne(ctx,
+				bson.M{"_id": student.ID, "pending_slip_count": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"pending_slip_count": pending}},
+			)
+		}
+		if err != nil {
+			log.Printf("Pending late slips of student %s could not be counted: %v", student.StudentID, err)
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("Counted the pending late slips of %d students", migrated)
+	}
+}
@@ -0,0 +1,37 @@
+package initialializers
+
+import (
+	"context"
+	"lateslip/mailtemplates"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MigrateOutboxSecrets flags emails of mailtemplates.SecretEvents queued before the
+// outbox knew about secret messages, and clears the bodies of those already sent or
+// dead-lettered
+func MigrateOutboxSecrets() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := DB.Collection("outbox")
+	filter := bson.M{"event": bson.M{"$in": mailtemplates.SecretEvents}, "secret": bson.M{"$ne": true}}
+	if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"secret": true}}); err != nil {
+		log.Printf("Failed to flag secret outbox messages: %v", err)
+		return
+	}
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"secret": true, "status": bson.M{"$in": []string{"sent", "dead"}}, "$or": []bson.M{{"text": bson.M{"$exists": true}}, {"html": bson.M{"$exists": true}}}},
+		bson.M{"$unset": bson.M{"text": "", "html": ""}},
+	)
+	if err != nil {
+		log.Printf("Failed to clear secret outbox messages: %v", err)
+		return
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Cleared the body of %d sent emails carrying a login link or code", result.ModifiedCount)
+	}
+}
@@ -0,0 +1,95 @@
+package initialializers
+
+import (
+	"context"
+	"lateslip/models"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrateScheduleTimes fills the structured day/time fields of schedules imported
+// before they existed. Rows that cannot be parsed are logged and left alone.
+func MigrateScheduleTimes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := DB.Collection("schedules")
+	cursor, err := collection.Find(ctx, bson.M{"start_minute": bson.M{"$exists": false}})
+	if err != nil {
+		log.Printf("Failed to look up schedules to migrate: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var schedule models.Schedule
+		if err := cursor.Decode(&schedule); err != nil {
+			continue
+		}
+		if err := schedule.Normalize(); err != nil {
+			log.Printf("Schedule %s (%s) could not be migrated: %v", schedule.ID.Hex(), schedule.ModuleCode, err)
+			continue
+		}
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": schedule.ID}, bson.M{"$set": bson.M{
+			"day":          schedule.Day,
+			"weekday":      schedule.Weekday,
+			"start_time":   schedule.StartTime,
+			"end_time":     schedule.EndTime,
+			"start_minute": schedule.StartMinute,
+			"end_minute":   schedule.EndMinute,
+		}})
+		if err != nil {
+			log.Printf("Failed to migrate schedule %s: %v", schedule.ID.Hex(), err)
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("Migrated %d schedules to structured times", migrated)
+	}
+}
+
+// MigrateScheduleKeys rewrites module codes and semesters stored before they were
+// normalised, so the natural key index and the import match them whatever their case.
+// A session that would clash with an existing one is logged and left alone.
+func MigrateScheduleKeys() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := DB.Collection("schedules")
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"module_code": 1, "semester": 1}))
+	if err != nil {
+		log.Printf("Failed to look up schedules to normalise: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var schedule models.Schedule
+		if err := cursor.Decode(&schedule); err != nil {
+			continue
+		}
+		moduleCode := models.NormalizeModuleCode(schedule.ModuleCode)
+		semester := models.NormalizeSemester(schedule.Semester)
+		if moduleCode == schedule.ModuleCode && semester == schedule.Semester {
+			continue
+		}
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": schedule.ID}, bson.M{"$set": bson.M{
+			"module_code": moduleCode,
+			"semester":    semester,
+		}})
+		if err != nil {
+			log.Printf("Schedule %s (%s) could not be normalised: %v", schedule.ID.Hex(), schedule.ModuleCode, err)
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("Normalised module code and semester of %d schedules", migrated)
+	}
+}
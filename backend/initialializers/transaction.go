@@ -0,0 +1,39 @@
+package initialializers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var warnNoTransactions sync.Once
+
+// WithTransaction runs fn inside a MongoDB transaction. Standalone servers do not
+// support transactions, so fn is run without one there and a warning is logged once.
+func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := DB.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	if transactionsUnsupported(err) {
+		warnNoTransactions.Do(func() {
+			log.Println("MongoDB does not support transactions (not a replica set); writes are not atomic")
+		})
+		return fn(ctx)
+	}
+	return err
+}
+
+func transactionsUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	// IllegalOperation: "Transaction numbers are only allowed on a replica set member or mongos"
+	return errors.As(err, &cmdErr) && cmdErr.Code == 20
+}
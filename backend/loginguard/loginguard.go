@@ -0,0 +1,183 @@
+package loginguard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Entry is the failed-login history of one key (an account or an IP address)
+type Entry struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Store keeps failed-login counts. The in-process store is enough for one server,
+// the Mongo store shares the counts between instances.
+type Store interface {
+	Get(ctx context.Context, key string) (Entry, error)
+	// Fail counts a failed attempt at now. The count starts over when the previous
+	// failure is older than window.
+	Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Entry, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy decides how long a key has to wait after failing
+type Policy struct {
+	Window       time.Duration // failures older than this are forgotten
+	FreeAttempts int           // failures allowed before delays start
+	MaxDelay     time.Duration // the delay doubles with every failure up to this
+	AccountLock  int           // failures that lock an account
+	IPLock       int           // failures that lock an IP address, which may be shared
+	LockDuration time.Duration
+}
+
+var DefaultPolicy = Policy{
+	Window:       15 * time.Minute,
+	FreeAttempts: 3,
+	MaxDelay:     time.Minute,
+	AccountLock:  10,
+	IPLock:       50,
+	LockDuration: 15 * time.Minute,
+}
+
+// VerificationPolicy throttles email verification. Wrong codes and resent emails share
+// one budget per account, so asking for a new code does not buy more guesses.
+var VerificationPolicy = Policy{
+	Window:       time.Hour,
+	FreeAttempts: 3,
+	MaxDelay:     time.Minute,
+	AccountLock:  10,
+	IPLock:       50,
+	LockDuration: time.Hour,
+}
+
+// Guard throttles attempts per account and per IP address
+type Guard struct {
+	Store  Store
+	Policy Policy
+	Scope  string // prefixes the keys, so guards sharing a collection count separately
+}
+
+// FromEnv builds a guard with the store selected by LOGIN_GUARD_STORE (memory or
+// mongo). The in-process store is used when the variable is not set. Logins use the
+// empty scope.
+func FromEnv(db *mongo.Database, scope string, policy Policy) (*Guard, error) {
+	switch store := strings.ToLower(strings.TrimSpace(os.Getenv("LOGIN_GUARD_STORE"))); store {
+	case "", "memory":
+		return &Guard{Store: NewMemoryStore(), Policy: policy, Scope: scope}, nil
+	case "mongo":
+		return &Guard{Store: NewMongoStore(db.Collection("login_throttle")), Policy: policy, Scope: scope}, nil
+	default:
+		return nil, fmt.Errorf("unknown LOGIN_GUARD_STORE %q", store)
+	}
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+type limitedKey struct {
+	key   string
+	limit int
+}
+
+// keys are the keys an attempt counts against. Without an email, e.g. a verification
+// link, only the IP address is throttled.
+func (g *Guard) keys(email, ip string) []limitedKey {
+	keys := []limitedKey{{g.Scope + ipKey(ip), g.Policy.IPLock}}
+	if strings.TrimSpace(email) != "" {
+		keys = append(keys, limitedKey{g.Scope + accountKey(email), g.Policy.AccountLock})
+	}
+	return keys
+}
+
+// delay is how long to wait after the given number of failures
+func (p Policy) delay(failures int) time.Duration {
+	if failures < p.FreeAttempts {
+		return 0
+	}
+	delay := time.Second
+	for i := p.FreeAttempts; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// wait is how long the entry has to wait at now; locked reports a lockout
+func (p Policy) wait(entry Entry, now time.Time) (time.Duration, bool) {
+	if now.Before(entry.LockedUntil) {
+		return entry.LockedUntil.Sub(now), true
+	}
+	if entry.Failures == 0 || now.Sub(entry.LastFailure) > p.Window {
+		return 0, false
+	}
+	if wait := entry.LastFailure.Add(p.delay(entry.Failures)).Sub(now); wait > 0 {
+		return wait, false
+	}
+	return 0, false
+}
+
+// Check returns how long the account and IP have to wait before the next attempt,
+// and whether that is because of a lockout rather than a delay
+func (g *Guard) Check(ctx context.Context, email, ip string, now time.Time) (time.Duration, bool, error) {
+	var longest time.Duration
+	var locked bool
+	for _, k := range g.keys(email, ip) {
+		entry, err := g.Store.Get(ctx, k.key)
+		if err != nil {
+			return 0, false, err
+		}
+		wait, isLock := g.Policy.wait(entry, now)
+		if wait > longest {
+			longest, locked = wait, isLock
+		}
+	}
+	return longest, locked, nil
+}
+
+// Fail counts a wrong password for the account and the IP, and locks whichever has
+// failed too often
+func (g *Guard) Fail(ctx context.Context, email, ip string, now time.Time) error {
+	for _, k := range g.keys(email, ip) {
+		entry, err := g.Store.Fail(ctx, k.key, now, g.Policy.Window)
+		if err != nil {
+			return err
+		}
+		if k.limit > 0 && entry.Failures >= k.limit {
+			if err := g.Store.Lock(ctx, k.key, now.Add(g.Policy.LockDuration)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Succeed forgets the account's failures after a correct password. The IP keeps its
+// count so one valid account cannot be used to keep guessing others.
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return g.Store.Reset(ctx, g.Scope+accountKey(email))
+}
+
+// Unlock lifts the lockout and delays of an account
+func (g *Guard) Unlock(ctx context.Context, email string) error {
+	return g.Store.Reset(ctx, g.Scope+accountKey(email))
+}
+
+// Status returns the failure history of an account
+func (g *Guard) Status(ctx context.Context, email string) (Entry, error) {
+	return g.Store.Get(ctx, g.Scope+accountKey(email))
+}
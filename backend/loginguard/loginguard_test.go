@@ -0,0 +1,161 @@
+package loginguard
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func TestPolicyDelay(t *testing.T) {
+	want := []time.Duration{0, 0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		16 * time.Second, 32 * time.Second, time.Minute, time.Minute, time.Minute}
+	for failures, delay := range want {
+		if got := DefaultPolicy.delay(failures); got != delay {
+			t.Errorf("delay(%d) = %v, want %v", failures, got, delay)
+		}
+	}
+
+	capped := Policy{FreeAttempts: 1, MaxDelay: 5 * time.Second}
+	if got := capped.delay(100); got != 5*time.Second {
+		t.Errorf("delay(100) = %v, want the 5s cap", got)
+	}
+}
+
+func TestPolicyWait(t *testing.T) {
+	tests := []struct {
+		name       string
+		entry      Entry
+		now        time.Time
+		wantWait   time.Duration
+		wantLocked bool
+	}{
+		{"no failures", Entry{}, start, 0, false},
+		{"free attempts", Entry{Failures: 2, LastFailure: start}, start, 0, false},
+		{"delayed", Entry{Failures: 5, LastFailure: start}, start.Add(time.Second), 3 * time.Second, false},
+		{"delay over", Entry{Failures: 5, LastFailure: start}, start.Add(4 * time.Second), 0, false},
+		{"outside window", Entry{Failures: 9, LastFailure: start}, start.Add(16 * time.Minute), 0, false},
+		{"locked", Entry{Failures: 10, LastFailure: start, LockedUntil: start.Add(15 * time.Minute)}, start.Add(5 * time.Minute), 10 * time.Minute, true},
+		{"lock expired", Entry{Failures: 10, LastFailure: start, LockedUntil: start.Add(15 * time.Minute)}, start.Add(15 * time.Minute), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, locked := DefaultPolicy.wait(tt.entry, tt.now)
+			if wait != tt.wantWait || locked != tt.wantLocked {
+				t.Fatalf("wait() = %v, %v, want %v, %v", wait, locked, tt.wantWait, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	window := 15 * time.Minute
+
+	for i := 1; i <= 3; i++ {
+		entry, err := store.Fail(ctx, "k", start.Add(time.Duration(i)*time.Minute), window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Failures != i {
+			t.Fatalf("Fail() #%d counted %d failures", i, entry.Failures)
+		}
+	}
+
+	// a failure after a quiet window starts the count over
+	entry, _ := store.Fail(ctx, "k", start.Add(20*time.Minute), window)
+	if entry.Failures != 1 {
+		t.Fatalf("Fail() after the window counted %d failures, want 1", entry.Failures)
+	}
+
+	until := start.Add(time.Hour)
+	if err := store.Lock(ctx, "k", until); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := store.Get(ctx, "k"); entry.Failures != 1 || !entry.LockedUntil.Equal(until) {
+		t.Fatalf("Get() after Lock() = %+v", entry)
+	}
+
+	// pruning keeps locked entries and drops stale ones
+	store.Fail(ctx, "stale", start.Add(21*time.Minute), window)
+	store.Fail(ctx, "other", start.Add(50*time.Minute), window)
+	if entry, _ := store.Get(ctx, "k"); entry.Failures != 1 {
+		t.Fatalf("locked entry was pruned: %+v", entry)
+	}
+	if entry, _ := store.Get(ctx, "stale"); entry.Failures != 0 {
+		t.Fatalf("stale entry was kept: %+v", entry)
+	}
+
+	if err := store.Reset(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := store.Get(ctx, "k"); entry != (Entry{}) {
+		t.Fatalf("Get() after Reset() = %+v", entry)
+	}
+}
+
+func TestGuardLocksAndUnlocks(t *testing.T) {
+	ctx := context.Background()
+	guard := &Guard{Store: NewMemoryStore(), Policy: DefaultPolicy}
+	now := start
+
+	for i := 0; i < DefaultPolicy.AccountLock; i++ {
+		wait, _, err := guard.Check(ctx, "Ada@Example.com", "10.0.0.1", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(wait)
+		if err := guard.Fail(ctx, "ada@example.com ", "10.0.0.1", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wait, locked, _ := guard.Check(ctx, "ada@example.com", "10.0.0.2", now)
+	if !locked || wait != DefaultPolicy.LockDuration {
+		t.Fatalf("Check() after %d failures = %v, %v, want a %v lock", DefaultPolicy.AccountLock, wait, locked, DefaultPolicy.LockDuration)
+	}
+
+	if err := guard.Unlock(ctx, "ADA@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, locked, _ := guard.Check(ctx, "ada@example.com", "10.0.0.2", now); wait != 0 || locked {
+		t.Fatalf("Check() after Unlock() = %v, %v", wait, locked)
+	}
+
+	// the IP keeps its count after the account is cleared
+	if entry, _ := guard.Store.Get(ctx, ipKey("10.0.0.1")); entry.Failures != DefaultPolicy.AccountLock {
+		t.Fatalf("IP failures = %d, want %d", entry.Failures, DefaultPolicy.AccountLock)
+	}
+}
+
+func TestGuardScopes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	logins := &Guard{Store: store, Policy: DefaultPolicy}
+	verification := &Guard{Store: store, Policy: VerificationPolicy, Scope: "verify:"}
+
+	for i := 0; i < VerificationPolicy.AccountLock; i++ {
+		if err := verification.Fail(ctx, "ada@example.com", "10.0.0.1", start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, locked, _ := verification.Check(ctx, "ada@example.com", "10.0.0.2", start); !locked {
+		t.Fatal("verification is not locked after the account used up its budget")
+	}
+	if wait, _, _ := logins.Check(ctx, "ada@example.com", "10.0.0.1", start); wait != 0 {
+		t.Fatalf("login Check() = %v, want the login scope untouched", wait)
+	}
+
+	// attempts without an email count for the IP only
+	if err := verification.Fail(ctx, "", "10.0.0.3", start); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := store.Get(ctx, "verify:"+accountKey("")); entry.Failures != 0 {
+		t.Fatalf("empty account failures = %d, want 0", entry.Failures)
+	}
+	if entry, _ := store.Get(ctx, "verify:"+ipKey("10.0.0.3")); entry.Failures != 1 {
+		t.Fatalf("IP failures = %d, want 1", entry.Failures)
+	}
+}
@@ -0,0 +1,68 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the counts in process; they are lost on restart
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+	pruned  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]Entry{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now, window)
+
+	entry := s.entries[key]
+	if now.Sub(entry.LastFailure) > window {
+		entry.Failures = 0
+	}
+	entry.Failures++
+	entry.LastFailure = now
+	s.entries[key] = entry
+	return entry, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entries[key]
+	entry.LockedUntil = until
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// prune drops entries that are neither locked nor inside the window, at most once a window
+func (s *MemoryStore) prune(now time.Time, window time.Duration) {
+	if now.Sub(s.pruned) < window {
+		return
+	}
+	s.pruned = now
+	for key, entry := range s.entries {
+		if now.Sub(entry.LastFailure) > window && !now.Before(entry.LockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package loginguard
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps the counts in a collection shared by every instance. Documents
+// carry an expires_at for a TTL index.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+type mongoEntry struct {
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure_at"`
+	LockedUntil time.Time `bson:"locked_until,omitempty"`
+}
+
+func (e mongoEntry) entry() Entry {
+	return Entry{Failures: e.Failures, LastFailure: e.LastFailure, LockedUntil: e.LockedUntil}
+}
+
+func (s *MongoStore) Get(ctx context.Context, key string) (Entry, error) {
+	var document mongoEntry
+	err := s.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return Entry{}, nil
+	}
+	return document.entry(), err
+}
+
+func (s *MongoStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Entry, error) {
+	// one atomic update, so concurrent attempts are all counted
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures": bson.M{"$cond": bson.A{
+			bson.M{"$gte": bson.A{"$last_failure_at", now.Add(-window)}},
+			bson.M{"$add": bson.A{"$failures", 1}},
+			1,
+		}},
+		"last_failure_at": now,
+		"expires_at":      bson.M{"$max": bson.A{"$locked_until", now.Add(window)}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var document mongoEntry
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&document)
+	return document.entry(), err
+}
+
+func (s *MongoStore) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{
+		"$set": bson.M{"locked_until": until},
+		"$max": bson.M{"expires_at": until},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (s *MongoStore) Reset(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,192 @@
+package mailtemplates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Events that have an email template
+const (
+	RequestSubmitted = "request_submitted"
+	Approved         = "approved"
+	Rejected         = "rejected"
+	QuotaWarning     = "quota_warning"
+	AdminInvite      = "admin_invite"
+	PasswordReset    = "password_reset"
+	VerifyEmail      = "verify_email"
+)
+
+var Events = []string{RequestSubmitted, Approved, Rejected, QuotaWarning, AdminInvite, PasswordReset, VerifyEmail}
+
+// SecretEvents are the events whose email carries a link or code that grants access
+// to an account, so its body must not be kept or shown once sent
+var SecretEvents = []string{AdminInvite, PasswordReset, VerifyEmail}
+
+// Secret reports whether event is one of SecretEvents
+func Secret(event string) bool {
+	return slices.Contains(SecretEvents, event)
+}
+
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+// Data is what every template is rendered with
+type Data struct {
+	RecipientName string
+	LateSlipID    string
+	StudentName   string
+	RosterID      string
+	Level         string
+	Semester      string
+	Reason        string
+	Status        string
+	Time          time.Time
+	Remaining     int
+	Limit         int
+	ModuleCode    string
+	ModuleName    string
+	RoomName      string
+	Instructor    string
+	Link          string    // action link of account emails, e.g. an admin invite
+	ExpiresAt     time.Time // when Link stops working
+	InvitedBy     string
+	Code          string // typed in instead of following Link
+}
+
+// Rendered is a ready to send email
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type eventTemplates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var loaded map[string]eventTemplates
+
+// Load parses the templates for every event. Files in dir (if set) replace the
+// built-in file with the same name, e.g. approved.html.tmpl or layout.html.tmpl.
+// An event can have its own layout in <event>.layout.html.tmpl / <event>.layout.txt.tmpl.
+func Load(dir string) error {
+	templates := make(map[string]eventTemplates, len(Events))
+	for _, event := range Events {
+		htmlTmpl, err := parseHTML(dir, event)
+		if err != nil {
+			return err
+		}
+		textTmpl, err := parseText(dir, event)
+		if err != nil {
+			return err
+		}
+		if textTmpl.Lookup("subject") == nil {
+			return fmt.Errorf("template %s.txt.tmpl does not define a subject", event)
+		}
+		templates[event] = eventTemplates{html: htmlTmpl, text: textTmpl}
+	}
+	loaded = templates
+	return nil
+}
+
+// Render produces the subject, plaintext and HTML parts for an event
+func Render(event string, data Data) (Rendered, error) {
+	if loaded == nil {
+		if err := Load(""); err != nil {
+			return Rendered{}, err
+		}
+	}
+	tmpl, ok := loaded[event]
+	if !ok {
+		return Rendered{}, fmt.Errorf("no email template for event %q", event)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "layout", data); err != nil {
+		return Rendered{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Rendered{}, err
+	}
+
+	return Rendered{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+func parseHTML(dir, event string) (*htmltemplate.Template, error) {
+	layout, err := readLayout(dir, event, "html")
+	if err != nil {
+		return nil, err
+	}
+	body, err := readTemplate(dir, event+".html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := htmltemplate.New(event).Parse(layout)
+	if err == nil {
+		tmpl, err = tmpl.Parse(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s.html.tmpl: %w", event, err)
+	}
+	return tmpl, nil
+}
+
+func parseText(dir, event string) (*texttemplate.Template, error) {
+	layout, err := readLayout(dir, event, "txt")
+	if err != nil {
+		return nil, err
+	}
+	body, err := readTemplate(dir, event+".txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := texttemplate.New(event).Parse(layout)
+	if err == nil {
+		tmpl, err = tmpl.Parse(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s.txt.tmpl: %w", event, err)
+	}
+	return tmpl, nil
+}
+
+// readLayout prefers the event's own layout over the shared one
+func readLayout(dir, event, ext string) (string, error) {
+	layout, err := readTemplate(dir, event+".layout."+ext+".tmpl")
+	if errors.Is(err, fs.ErrNotExist) {
+		return readTemplate(dir, "layout."+ext+".tmpl")
+	}
+	return layout, err
+}
+
+func readTemplate(dir, name string) (string, error) {
+	if dir != "" {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return string(content), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	content, err := defaults.ReadFile("defaults/" + name)
+	return string(content), err
+}
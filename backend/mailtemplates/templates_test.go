@@ -0,0 +1,153 @@
+package mailtemplates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sample = Data{
+	RecipientName: "Ada Lovelace",
+	LateSlipID:    "65f0c0ffee",
+	StudentName:   "Ada Lovelace",
+	RosterID:      "R-001",
+	Level:         "4",
+	Semester:      "SPRING 2026",
+	Reason:        "Bus <late> & rain",
+	Status:        "approved",
+	Time:          time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC),
+	Remaining:     1,
+	Limit:         3,
+	ModuleCode:    "CS101",
+	ModuleName:    "Programming",
+	RoomName:      "Lab 2",
+	Instructor:    "Grace Hopper",
+	Link:          "https://app.example.com/verify-email?token=abc",
+	ExpiresAt:     time.Date(2026, 3, 3, 9, 15, 0, 0, time.UTC),
+	InvitedBy:     "Grace Hopper",
+	Code:          "042137",
+}
+
+func TestRenderEveryEvent(t *testing.T) {
+	if err := Load(""); err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range Events {
+		t.Run(event, func(t *testing.T) {
+			rendered, err := Render(event, sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rendered.Subject == "" || strings.ContainsAny(rendered.Subject, "\r\n") {
+				t.Errorf("subject %q is empty or spans lines", rendered.Subject)
+			}
+			if !strings.HasPrefix(rendered.Text, "Hello") || !strings.HasSuffix(rendered.Text, "\n") {
+				t.Errorf("text part:\n%s", rendered.Text)
+			}
+			if !strings.Contains(rendered.HTML, "<p>Hello") || strings.Contains(rendered.HTML, "<late>") {
+				t.Errorf("HTML part is missing the greeting or does not escape data:\n%s", rendered.HTML)
+			}
+		})
+	}
+}
+
+func TestRenderClass(t *testing.T) {
+	rendered, err := Render(Approved, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered.Text, "Class: CS101 Programming, Lab 2 (Grace Hopper)") {
+		t.Errorf("text part does not name the class:\n%s", rendered.Text)
+	}
+
+	withoutClass := sample
+	withoutClass.ModuleCode = ""
+	rendered, err = Render(Approved, withoutClass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(rendered.Text, "Class:") || strings.Contains(rendered.HTML, "Class") {
+		t.Error("slips without a class should not show the class line")
+	}
+}
+
+func TestRenderAccountEmails(t *testing.T) {
+	for _, event := range []string{AdminInvite, PasswordReset, VerifyEmail} {
+		rendered, err := Render(event, sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(rendered.Text, sample.Link) {
+			t.Errorf("%s text part does not contain the link", event)
+		}
+	}
+	rendered, _ := Render(VerifyEmail, sample)
+	if !strings.Contains(rendered.Text, sample.Code) || !strings.Contains(rendered.HTML, sample.Code) {
+		t.Error("verification email does not contain the code")
+	}
+}
+
+func TestRenderUnknownEvent(t *testing.T) {
+	if _, err := Render("nope", sample); err == nil {
+		t.Fatal("Render() of an unknown event succeeded")
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	t.Cleanup(func() { Load("") })
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("approved.txt.tmpl", `{{define "subject"}}  Approved:
+ {{.LateSlipID}} {{end}}{{define "content"}}Custom body{{end}}`)
+	write("rejected.layout.txt.tmpl", `{{define "layout"}}REJECTED {{template "content" .}}{{end}}`)
+
+	if err := Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := Render(Approved, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Subject != "Approved: 65f0c0ffee" {
+		t.Errorf("subject = %q, want the override with whitespace collapsed", rendered.Subject)
+	}
+	if !strings.Contains(rendered.Text, "Custom body") {
+		t.Errorf("text part does not use the override:\n%s", rendered.Text)
+	}
+	if !strings.Contains(rendered.HTML, "approved") {
+		t.Errorf("HTML part should still be the built-in template:\n%s", rendered.HTML)
+	}
+
+	rendered, err = Render(Rejected, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rendered.Text, "REJECTED ") {
+		t.Errorf("text part does not use the event layout:\n%s", rendered.Text)
+	}
+
+	write("quota_warning.txt.tmpl", `{{define "content"}}no subject{{end}}`)
+	if err := Load(dir); err == nil {
+		t.Fatal("Load() accepted a text template without a subject")
+	}
+	write("quota_warning.txt.tmpl", `{{define "subject"}}{{.Broken}{{end}}`)
+	if err := Load(dir); err == nil {
+		t.Fatal("Load() accepted a template that does not parse")
+	}
+}
+
+func TestSecretEvents(t *testing.T) {
+	for _, event := range Events {
+		want := event == AdminInvite || event == PasswordReset || event == VerifyEmail
+		if Secret(event) != want {
+			t.Errorf("Secret(%q) = %v, want %v", event, !want, want)
+		}
+	}
+}
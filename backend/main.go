@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"lateslip/controllers"
+	"log"
 	"lateslip/events"
+	"lateslip/outbox"
 
 	"lateslip/initialializers"
 	"lateslip/middleware"
@@ -13,10 +16,31 @@ import (
 func init() {
 	initialializers.LoadEnvVariables()
 	initialializers.ConnectToDB()
+	initialializers.LoadLateSlipLimits()
+	initialializers.LoadNotifier()
+	initialializers.LoadAppBaseURL()
+	initialializers.LoadPublicBaseURL()
+	initialializers.LoadLoginGuard()
+	initialializers.LoadEmailTemplates()
+	initialializers.LoadCampusTimezone()
+	initialializers.LoadTrustedProxies()
+	initialializers.MigrateScheduleTimes()
+	initialializers.MigrateScheduleKeys()
+	initialializers.MigrateOutboxSecrets()
+	initialializers.MigrateEmails()
+	initialializers.MigratePendingSlipCounts()
+	initialializers.EnsureIndexes()
+	initialializers.FailInterruptedImportJobs()
 }
 
 func main() {
+	// Deliver queued emails in the background
+	go outbox.StartWorker(context.Background())
+
 	r := gin.Default()
+	if err := r.SetTrustedProxies(initialializers.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	r.Use(middleware.RequestIDMiddleware())
 
@@ -24,27 +48,74 @@ func main() {
 	{
 		userRoutes.POST("/student/register", controllers.Register)
 		userRoutes.POST("/student/login", controllers.Login)
+		userRoutes.POST("/student/verify-email", controllers.VerifyEmail)
+		userRoutes.POST("/student/verify-email/resend", controllers.ResendEmailVerification)
 		userRoutes.POST("/admin/register", controllers.AdminRegister)
 		userRoutes.POST("/admin/login", controllers.AdminLogin)
+		userRoutes.POST("/instructor/login", controllers.Login)
+		userRoutes.GET("/lateslips/:id/verify", controllers.VerifyLateSlip)
+		userRoutes.POST("/auth/refresh", controllers.RefreshSession)
+		userRoutes.POST("/auth/logout", controllers.Logout)
+		userRoutes.POST("/auth/password/forgot", controllers.ForgotPassword)
+		userRoutes.POST("/auth/password/reset", controllers.ResetPassword)
+
+	}
 
+	authRoutes := r.Group("/auth").Use(middleware.AuthMiddleware())
+	{
+		authRoutes.POST("/logout-all", controllers.LogoutAll)
+		authRoutes.PUT("/password", controllers.ChangePassword)
 	}
 
 	// Add WebSocket routes
 	studentRoutes := r.Group("/student").Use(middleware.AuthMiddleware(), middleware.RequireRole("student"))
 	{
 		studentRoutes.POST("/requestLateSlip", controllers.RequestLateSlip)
+		studentRoutes.GET("/schedule", controllers.GetStudentSchedule)
+		studentRoutes.GET("/lateslips/:id/pdf", controllers.GetLateSlipPDF)
 		// Replace SSE with WebSocket endpoint for students
 		studentRoutes.GET("/ws", events.WebSocketHandler)
 	}
 
+	instructorRoutes := r.Group("/instructor").Use(middleware.AuthMiddleware(), middleware.RequireRole("instructor"))
+	{
+		instructorRoutes.GET("/lateslips", controllers.GetInstructorLateSlips)
+		instructorRoutes.PUT("/lateslips/:id/use", controllers.MarkLateSlipUsed)
+		instructorRoutes.GET("/ws", controllers.InstructorWebSocket)
+	}
+
 	adminRoutes := r.Group("/admin").Use(middleware.AuthMiddleware(), middleware.RequireRole("admin"))
 	{
 		adminRoutes.PUT("/lateslips/approve", controllers.ApproveLateSlip)
 		adminRoutes.GET("/lateslips", controllers.GetAllLateSlips)
 		adminRoutes.POST("/uploadStudentData", controllers.UploadStudentData)
 		adminRoutes.GET("/lateslips/pending", controllers.GetAllPendingLateSlip)
+		adminRoutes.GET("/lateslips/export", controllers.ExportLateSlips)
+		adminRoutes.GET("/lateslips/:id/pdf", controllers.GetLateSlipPDF)
+		adminRoutes.POST("/instructors", controllers.CreateInstructor)
+		adminRoutes.GET("/instructors", controllers.GetInstructors)
+		adminRoutes.POST("/invites", controllers.CreateAdminInvite)
+		adminRoutes.GET("/invites", controllers.GetAdminInvites)
+		adminRoutes.DELETE("/invites/:id", controllers.RevokeAdminInvite)
+		adminRoutes.PUT("/users/:id/disable", controllers.DisableUser)
+		adminRoutes.PUT("/users/:id/enable", controllers.EnableUser)
+		adminRoutes.POST("/users/:id/unlock", controllers.UnlockUser)
+		adminRoutes.GET("/login-attempts", controllers.GetLoginAttempts)
 		adminRoutes.PUT("/lateslips/reject", controllers.RejectLateSlip)
 		adminRoutes.POST("/uploadScheduleData", controllers.UploadScheduleData)
+		adminRoutes.POST("/imports/previews/:token/commit", controllers.CommitImportPreview)
+		adminRoutes.GET("/imports/reports/:token", controllers.DownloadImportReport)
+		adminRoutes.GET("/imports/:id", controllers.GetImportJob)
+		adminRoutes.GET("/imports/history", controllers.GetImportHistory)
+		adminRoutes.POST("/imports/history/:id/rollback", controllers.RollbackImport)
+		adminRoutes.GET("/lateslips/:id/deliveries", controllers.GetLateSlipDeliveries)
+		adminRoutes.GET("/outbox", controllers.GetOutboxMessages)
+		adminRoutes.POST("/outbox/:id/replay", controllers.ReplayOutboxMessage)
+		adminRoutes.GET("/schedules", controllers.GetSchedules)
+		adminRoutes.POST("/schedules", controllers.CreateSchedule)
+		adminRoutes.GET("/schedules/:id", controllers.GetSchedule)
+		adminRoutes.PUT("/schedules/:id", controllers.UpdateSchedule)
+		adminRoutes.DELETE("/schedules/:id", controllers.DeleteSchedule)
 		// Replace SSE with WebSocket endpoint for admins
 		adminRoutes.GET("/ws", events.WebSocketHandler)
 	}
@@ -1,12 +1,12 @@
 package middleware
 
 import (
+	"errors"
+	"lateslip/sessions"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // AuthMiddleware validates JWT tokens and sets user_id and role in the context
@@ -29,39 +29,37 @@ func AuthMiddleware() gin.HandlerFunc {
         }
         tokenString := parts[1]
 
-        // Get JWT secret
-        secretKey := []byte(os.Getenv("JWT_SECRET"))
-        if len(secretKey) == 0 {
+        // Parse and validate token
+        claims, err := sessions.ParseAccessToken(tokenString)
+        if errors.Is(err, sessions.ErrNoSecret) {
             c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT secret not configured"})
             c.Abort()
             return
         }
-
-        // Parse and validate token
-        token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-            // Verify signing method
-            if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-                return nil, jwt.ErrSignatureInvalid
-            }
-            return secretKey, nil
-        })
-        if err != nil || !token.Valid {
+        if err != nil {
             c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
             c.Abort()
             return
         }
 
-        // Extract claims
-        claims, ok := token.Claims.(jwt.MapClaims)
-        if !ok {
-            c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+        // Refuse tokens revoked by logout or a disabled account
+        revoked, err := sessions.IsRevoked(c.Request.Context(), claims.ID)
+        if err != nil {
+            c.Error(err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token"})
+            c.Abort()
+            return
+        }
+        if revoked {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
             c.Abort()
             return
         }
 
-        // Set user_id and role in context
-        c.Set("user_id", claims["user_id"])
-        c.Set("role", claims["role"])
+        // Set user_id, role and session in context
+        c.Set("user_id", claims.UserID)
+        c.Set("role", claims.Role)
+        c.Set("session_id", claims.SessionID)
         c.Next()
     }
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailDelivery records one attempt to send an email to one recipient
+type EmailDelivery struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LateSlipID primitive.ObjectID `bson:"late_slip_id,omitempty" json:"late_slip_id,omitempty"`
+	OutboxID   primitive.ObjectID `bson:"outbox_id,omitempty" json:"outbox_id,omitempty"`
+	Event      string             `bson:"event" json:"event"`
+	Recipient  string             `bson:"recipient" json:"recipient"`
+	Subject    string             `bson:"subject" json:"subject"`
+	Status     string             `bson:"status" json:"status"` // sent or failed
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
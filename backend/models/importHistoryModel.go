@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportHistory records what an applied roster or timetable import changed, so that
+// it can be traced to its upload and rolled back
+type ImportHistory struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	JobID        primitive.ObjectID   `bson:"job_id" json:"job_id"`
+	Kind         string               `bson:"kind" json:"kind"` // students or schedules
+	FileName     string               `bson:"file_name" json:"file_name"`
+	FileHash     string               `bson:"file_hash" json:"file_hash"` // SHA-256 of the uploaded file
+	UploadedBy   string               `bson:"uploaded_by" json:"uploaded_by"`
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+	Created      []primitive.ObjectID `bson:"created" json:"created"`
+	Changed      []ImportSnapshot     `bson:"changed" json:"changed"`
+	Removed      []ImportSnapshot     `bson:"removed" json:"removed"`
+	RolledBackAt *time.Time           `bson:"rolled_back_at,omitempty" json:"rolled_back_at,omitempty"`
+	RolledBackBy string               `bson:"rolled_back_by,omitempty" json:"rolled_back_by,omitempty"`
+}
+
+// ImportSnapshot is a document as it was before the import touched it
+type ImportSnapshot struct {
+	ID     primitive.ObjectID `bson:"id" json:"id"`
+	Fields []string           `bson:"fields,omitempty" json:"fields,omitempty"` // fields the import wrote; empty for removed documents
+	Before bson.M             `bson:"before,omitempty" json:"before,omitempty"`
+}
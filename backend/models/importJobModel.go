@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportJob tracks an upload or preview commit that is processed in the background
+type ImportJob struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind       string             `bson:"kind" json:"kind"`     // students or schedules
+	Status     string             `bson:"status" json:"status"` // queued, running, succeeded or failed
+	Stage      string             `bson:"stage,omitempty" json:"stage,omitempty"`
+	DryRun     bool               `bson:"dry_run,omitempty" json:"dry_run,omitempty"`
+	FileName   string             `bson:"file_name,omitempty" json:"file_name,omitempty"`
+	FileHash   string             `bson:"file_hash,omitempty" json:"file_hash,omitempty"`
+	Total      int                `bson:"total" json:"total"`         // data rows in the file
+	Processed  int                `bson:"processed" json:"processed"` // rows handled so far
+	Invalid    int                `bson:"invalid" json:"invalid"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	Result     bson.M             `bson:"result,omitempty" json:"result,omitempty"` // what the upload endpoint used to return
+	CreatedBy  string             `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	StartedAt  *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
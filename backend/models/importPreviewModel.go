@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportPreview holds the valid records of a dry-run upload until they are committed or expire
+type ImportPreview struct {
+	ID                primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Token             string               `bson:"token" json:"token"`
+	Kind              string               `bson:"kind" json:"kind"` // students or schedules
+	FileName          string               `bson:"file_name" json:"file_name"`
+	FileHash          string               `bson:"file_hash,omitempty" json:"file_hash,omitempty"`
+	ReplaceSemester   bool                 `bson:"replace_semester,omitempty" json:"replace_semester,omitempty"`
+	DeactivateMissing bool                 `bson:"deactivate_missing,omitempty" json:"deactivate_missing,omitempty"`
+	Students          []Student            `bson:"students,omitempty" json:"-"`
+	Schedules         []Schedule           `bson:"schedules,omitempty" json:"-"`
+	Rows              []ImportPreviewRow   `bson:"rows,omitempty" json:"-"`    // what the preview showed for each record, in the same order
+	Removed           []primitive.ObjectID `bson:"removed,omitempty" json:"-"` // students it would deactivate or sessions it would remove
+	CreatedBy         string               `bson:"created_by" json:"created_by"`
+	CreatedAt         time.Time            `bson:"created_at" json:"created_at"`
+	ExpiresAt         time.Time            `bson:"expires_at" json:"expires_at"`
+}
+
+// ImportPreviewRow is the sheet row a previewed record came from and what the import
+// planned to do with it
+type ImportPreviewRow struct {
+	Row     int      `bson:"row"`
+	Action  string   `bson:"action"`
+	Changes []string `bson:"changes,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportReport keeps an uploaded sheet with its validation errors so it can be
+// downloaded as a workbook, fixed and uploaded again
+type ImportReport struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token     string             `bson:"token" json:"token"`
+	Kind      string             `bson:"kind" json:"kind"` // students or schedules
+	FileName  string             `bson:"file_name" json:"file_name"`
+	Header    []string           `bson:"header" json:"header"`
+	Rows      []ImportReportRow  `bson:"rows" json:"rows"`
+	CreatedBy string             `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+type ImportReportRow struct {
+	Row        int      `bson:"row" json:"row"`
+	Cells      []string `bson:"cells" json:"cells"`
+	Errors     []string `bson:"errors,omitempty" json:"errors,omitempty"`
+	BadColumns []int    `bson:"bad_columns,omitempty" json:"bad_columns,omitempty"`
+}
@@ -8,10 +8,36 @@ import (
 
 type LateSlip struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	StudentID primitive.ObjectID `bson:"student_id" json:"student_id"`
+	StudentID primitive.ObjectID `bson:"student_id" json:"student_id"` // user account that requested the slip
 	Reason    string             `bson:"reason" json:"reason" binding:"required"`
 	Status    string             `bson:"status" json:"status" binding:"required,oneof=pending approved rejected"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 	RequestID string             `bson:"request_id,omitempty" json:"request_id"`
+
+	// roster details copied from the Student record when the slip is requested
+	StudentRecordID primitive.ObjectID `bson:"student_record_id,omitempty" json:"student_record_id,omitempty"`
+	RosterID        string             `bson:"roster_id,omitempty" json:"roster_id,omitempty"`
+	StudentName     string             `bson:"student_name,omitempty" json:"student_name,omitempty"`
+	Level           string             `bson:"level,omitempty" json:"level,omitempty"`
+	Semester        string             `bson:"semester,omitempty" json:"semester,omitempty"`
+
+	// class the student was late for, copied from the Schedule
+	ScheduleID     primitive.ObjectID `bson:"schedule_id,omitempty" json:"schedule_id,omitempty"`
+	ModuleCode     string             `bson:"module_code,omitempty" json:"module_code,omitempty"`
+	ModuleName     string             `bson:"module_name,omitempty" json:"module_name,omitempty"`
+	RoomName       string             `bson:"room_name,omitempty" json:"room_name,omitempty"`
+	InstructorName string             `bson:"instructor_name,omitempty" json:"instructor_name,omitempty"`
+	ClassStartTime string             `bson:"class_start_time,omitempty" json:"class_start_time,omitempty"`
+
+	// admin who approved or rejected the slip; approved slips can be shown until ValidUntil
+	DecidedBy     string     `bson:"decided_by,omitempty" json:"decided_by,omitempty"` // admin user ID
+	DecidedByName string     `bson:"decided_by_name,omitempty" json:"decided_by_name,omitempty"`
+	DecidedAt     *time.Time `bson:"decided_at,omitempty" json:"decided_at,omitempty"`
+	ValidUntil    *time.Time `bson:"valid_until,omitempty" json:"valid_until,omitempty"`
+
+	// set when the instructor accepts the slip at the door; a used slip cannot be shown again
+	UsedAt     *time.Time `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	UsedBy     string     `bson:"used_by,omitempty" json:"used_by,omitempty"` // instructor user ID
+	UsedByName string     `bson:"used_by_name,omitempty" json:"used_by_name,omitempty"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginAttempt is the audit record of one login, successful or not
+type LoginAttempt struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email     string             `bson:"email" json:"email"`
+	UserID    string             `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Portal    string             `bson:"portal" json:"portal"` // login endpoint used: student, instructor or admin
+	IP        string             `bson:"ip" json:"ip"`
+	UserAgent string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Success   bool               `bson:"success" json:"success"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"` // why it failed
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
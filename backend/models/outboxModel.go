@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxMessage is an email waiting to be delivered by the outbox worker
+type OutboxMessage struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LateSlipID    primitive.ObjectID `bson:"late_slip_id,omitempty" json:"late_slip_id,omitempty"`
+	Event         string             `bson:"event" json:"event"`
+	ToName        string             `bson:"to_name" json:"to_name"`
+	ToEmail       string             `bson:"to_email" json:"to_email"`
+	Subject       string             `bson:"subject" json:"subject"`
+	Text          string             `bson:"text,omitempty" json:"text,omitempty"`
+	HTML          string             `bson:"html,omitempty" json:"html,omitempty"`
+	Status        string             `bson:"status" json:"status"` // pending, sending, sent or dead
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   time.Time          `bson:"locked_until,omitempty" json:"-"`
+	SentAt        *time.Time         `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	Secret        bool               `bson:"secret,omitempty" json:"secret,omitempty"` // body holds a login link or code, cleared once done
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset is an emailed link for setting a new password. Only the hash of the
+// token is stored, and it can be used once.
+type PasswordReset struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TokenHash   string             `bson:"token_hash" json:"-"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RequestedIP string             `bson:"requested_ip,omitempty" json:"requested_ip,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expires_at"`
+	UsedAt      *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+}
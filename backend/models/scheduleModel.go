@@ -1,22 +1,109 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// TODO: need to update the model
 type Schedule struct {
 	ID             primitive.ObjectID `bson:"_id" json:"id"`
 	ModuleCode     string             `bson:"module_code" json:"module_code"`
 	ModuleName     string             `bson:"module_name" json:"module_name"`
-	StartTime      string             `bson:"start_time" json:"start_time"` // string for now
-	EndTime        string             `bson:"end_time" json:"end_time"`     // string for now
-	Day            string             `bson:"day" json:"day"`
+	StartTime      string             `bson:"start_time" json:"start_time"` // normalised "15:04"
+	EndTime        string             `bson:"end_time" json:"end_time"`     // normalised "15:04"
+	Day            string             `bson:"day" json:"day"`               // normalised weekday name
+	Weekday        time.Weekday       `bson:"weekday" json:"weekday"`
+	StartMinute    int                `bson:"start_minute" json:"start_minute"` // minutes since midnight, campus time
+	EndMinute      int                `bson:"end_minute" json:"end_minute"`
 	RoomName       string             `bson:"room_name" json:"room_name"`
 	InstructorName string             `bson:"instructor_name" json:"instructor_name"`
 	Semester       string             `bson:"semester" json:"semester"`
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
 }
+
+// NormalizeModuleCode is the stored form of a module code, e.g. " cs4001" -> "CS4001"
+func NormalizeModuleCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// NormalizeSemester is the stored form of a semester, so "fall  2024" and "Fall 2024"
+// are the same session key
+func NormalizeSemester(semester string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(semester), " "))
+}
+
+// Normalize parses Day, StartTime and EndTime into the structured fields and
+// rewrites the strings, module code and semester in their canonical form
+func (s *Schedule) Normalize() error {
+	weekday, err := ParseWeekday(s.Day)
+	if err != nil {
+		return err
+	}
+	start, err := ParseClock(s.StartTime)
+	if err != nil {
+		return fmt.Errorf("invalid start time: %w", err)
+	}
+	end, err := ParseClock(s.EndTime)
+	if err != nil {
+		return fmt.Errorf("invalid end time: %w", err)
+	}
+	if end <= start {
+		return fmt.Errorf("end time %s is not after start time %s", s.EndTime, s.StartTime)
+	}
+
+	s.ModuleCode = NormalizeModuleCode(s.ModuleCode)
+	s.Semester = NormalizeSemester(s.Semester)
+	s.Weekday = weekday
+	s.Day = weekday.String()
+	s.StartMinute = start
+	s.EndMinute = end
+	s.StartTime = FormatClock(start)
+	s.EndTime = FormatClock(end)
+	return nil
+}
+
+// ActiveAt reports whether the session is running at the given instant
+// (which must already be in campus time)
+func (s Schedule) ActiveAt(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	return s.Weekday == t.Weekday() && s.StartMinute <= minute && minute < s.EndMinute
+}
+
+// Overlaps reports whether two sessions share any time on the same day
+func (s Schedule) Overlaps(other Schedule) bool {
+	return s.Weekday == other.Weekday && s.StartMinute < other.EndMinute && other.StartMinute < s.EndMinute
+}
+
+// ParseWeekday accepts full or abbreviated English day names ("Monday", "mon", "Tues")
+func ParseWeekday(value string) (time.Weekday, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if len(v) >= 2 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			name := strings.ToLower(day.String())
+			if v == name || (len(v) >= 3 && strings.HasPrefix(name, v)) || v == name[:2] {
+				return day, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q", value)
+}
+
+// ParseClock turns a time of day like "09:30", "9:30 AM" or "14:00:00" into minutes since midnight
+func ParseClock(value string) (int, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	for _, layout := range []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "03:04 PM", "3 PM", "3PM", "15.04"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Hour()*60 + t.Minute(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q", value)
+}
+
+// FormatClock renders minutes since midnight as "15:04"
+func FormatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
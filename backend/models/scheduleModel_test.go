@@ -0,0 +1,108 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"09:30", 570, false},
+		{"9:30", 570, false},
+		{" 14:00:00 ", 840, false},
+		{"9:30 AM", 570, false},
+		{"9:30am", 570, false},
+		{"12:15 PM", 735, false},
+		{"12:15 AM", 15, false},
+		{"03:45 pm", 945, false},
+		{"3 PM", 900, false},
+		{"11pm", 1380, false},
+		{"14.30", 870, false},
+		{"00:00", 0, false},
+		{"23:59", 1439, false},
+		{"24:00", 0, true},
+		{"9:60", 0, true},
+		{"13:00 PM", 0, true},
+		{"noon", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseClock(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseClock(%q) = %d, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseClock(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Weekday
+		wantErr bool
+	}{
+		{"Monday", time.Monday, false},
+		{" monday ", time.Monday, false},
+		{"MON", time.Monday, false},
+		{"Tues", time.Tuesday, false},
+		{"tu", time.Tuesday, false},
+		{"th", time.Thursday, false},
+		{"Thur", time.Thursday, false},
+		{"sa", time.Saturday, false},
+		{"Sun", time.Sunday, false},
+		{"s", 0, true},
+		{"t", 0, true},
+		{"mo n", 0, true},
+		{"Mondays", 0, true},
+		{"fr1", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseWeekday(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseWeekday(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseWeekday(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNormalize(t *testing.T) {
+	s := Schedule{ModuleCode: " cs4001 ", Day: "wed", StartTime: "9:05 am", EndTime: "10:35", Semester: " fall   2024 "}
+	if err := s.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	want := Schedule{ModuleCode: "CS4001", Day: "Wednesday", Weekday: time.Wednesday, StartTime: "09:05", EndTime: "10:35",
+		StartMinute: 545, EndMinute: 635, Semester: "FALL 2024"}
+	if s != want {
+		t.Fatalf("Normalize() = %+v, want %+v", s, want)
+	}
+
+	for _, bad := range []Schedule{
+		{Day: "someday", StartTime: "09:00", EndTime: "10:00"},
+		{Day: "Mon", StartTime: "9", EndTime: "10:00"},
+		{Day: "Mon", StartTime: "09:00", EndTime: "late"},
+		{Day: "Mon", StartTime: "10:00", EndTime: "10:00"},
+	} {
+		if err := bad.Normalize(); err == nil {
+			t.Errorf("Normalize() accepted %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is one link in a login session's chain of refresh tokens. Every refresh
+// rotates it; all tokens of a login share a FamilyID so the session can be revoked
+// as a whole. Only the hash of the token is stored.
+type RefreshToken struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TokenHash       string             `bson:"token_hash" json:"-"`
+	FamilyID        string             `bson:"family_id" json:"family_id"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	AccessJTI       string             `bson:"access_jti" json:"-"` // access token issued alongside
+	AccessExpiresAt time.Time          `bson:"access_expires_at" json:"-"`
+	UserAgent       string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IP              string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt       time.Time          `bson:"expires_at" json:"expires_at"`
+	RotatedAt       *time.Time         `bson:"rotated_at,omitempty" json:"rotated_at,omitempty"`
+	RevokedAt       *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// RevokedToken is an access token (by its jti) that must be refused before it expires
+type RevokedToken struct {
+	JTI       string    `bson:"_id" json:"jti"`
+	UserID    string    `bson:"user_id" json:"user_id"`
+	RevokedAt time.Time `bson:"revoked_at" json:"revoked_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
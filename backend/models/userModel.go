@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,7 +12,21 @@ type User struct {
     Fullname  string            `bson:"fullname" json:"fullname" binding:"required"`
     Password  string            `bson:"password" json:"password" binding:"required"`  
     Email     string            `bson:"email" json:"email" binding:"required,email"`
-    Role      string            `bson:"role" json:"role" binding:"oneof=student admin"`
+    Role      string            `bson:"role" json:"role" binding:"oneof=student admin instructor"`
+    StudentID primitive.ObjectID `bson:"student_id,omitempty" json:"student_id,omitempty"` // roster record in the students collection
+    InstructorName string        `bson:"instructor_name,omitempty" json:"instructor_name,omitempty"` // name used in Schedule.InstructorName
+    Disabled  bool              `bson:"disabled,omitempty" json:"disabled,omitempty"`
+    DisabledAt *time.Time       `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+    PasswordChangedAt *time.Time `bson:"password_changed_at,omitempty" json:"password_changed_at,omitempty"`
+    PendingVerification bool     `bson:"pending_verification,omitempty" json:"pending_verification,omitempty"` // email not confirmed yet
+    EmailVerifiedAt *time.Time   `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+    CodeAttempts int             `bson:"code_attempts,omitempty" json:"-"` // verification codes entered, across every email sent
     CreatedAt time.Time         `bson:"created_at" json:"created_at"`
     UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
 }
+
+// NormalizeEmail is the stored form of an email address. Users and roster students are
+// written and looked up by it, so "Ada@College.edu " and "ada@college.edu" are one account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileNotifier writes each email as an .eml file into a directory, for development and tests
+type FileNotifier struct {
+	dir  string
+	from Sender
+}
+
+func NewFileNotifier(dir string, from Sender) (*FileNotifier, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail drop directory: %w", err)
+	}
+	if from.Email == "" {
+		from.Email = "lateslip@localhost"
+	}
+	return &FileNotifier{dir: dir, from: from}, nil
+}
+
+func (n *FileNotifier) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body, err := buildMIME(n.from, msg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), randomToken(4))
+	path := filepath.Join(n.dir, name)
+
+	// write to a temp file first so readers never see a partial message
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	log.Printf("Email to %s written to %s", msg.ToEmail, path)
+	return nil
+}
@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Message is a single email to a single recipient
+type Message struct {
+	ToName  string
+	ToEmail string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender identifies who outgoing emails are from
+type Sender struct {
+	Name  string
+	Email string
+}
+
+// Notifier delivers emails through a concrete backend
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv builds the notifier selected by NOTIFIER_BACKEND (sendgrid, smtp or file).
+// SendGrid is used when the variable is not set.
+func FromEnv() (Notifier, error) {
+	from := Sender{
+		Name:  envOr("MAIL_FROM_NAME", "HeraldSync Late Slip System"),
+		Email: envOr("MAIL_FROM_EMAIL", os.Getenv("SENDGRID_FROM_EMAIL")),
+	}
+
+	switch backend := strings.ToLower(strings.TrimSpace(os.Getenv("NOTIFIER_BACKEND"))); backend {
+	case "", "sendgrid":
+		return NewSendGridNotifier(os.Getenv("SENDGRID_API_KEY"), from), nil
+	case "smtp":
+		port := 587
+		if value := os.Getenv("SMTP_PORT"); value != "" {
+			p, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SMTP_PORT %q", value)
+			}
+			port = p
+		}
+		if os.Getenv("SMTP_HOST") == "" {
+			return nil, fmt.Errorf("SMTP_HOST is not set")
+		}
+		return NewSMTPNotifier(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "file":
+		return NewFileNotifier(envOr("MAIL_DROP_DIR", "maildrop"), from)
+	default:
+		return nil, fmt.Errorf("unknown NOTIFIER_BACKEND %q", backend)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
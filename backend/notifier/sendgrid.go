@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+// SendGridNotifier sends emails through the SendGrid API
+type SendGridNotifier struct {
+	apiKey string
+	from   Sender
+}
+
+func NewSendGridNotifier(apiKey string, from Sender) *SendGridNotifier {
+	return &SendGridNotifier{apiKey: apiKey, from: from}
+}
+
+func (n *SendGridNotifier) Send(ctx context.Context, msg Message) error {
+	// Check if API key is set
+	if n.apiKey == "" {
+		return fmt.Errorf("SendGrid API key not found")
+	}
+	if n.from.Email == "" {
+		return fmt.Errorf("SendGrid from email not found")
+	}
+
+	from := mail.NewEmail(n.from.Name, n.from.Email)
+	to := mail.NewEmail(msg.ToName, msg.ToEmail)
+	message := mail.NewSingleEmail(from, msg.Subject, to, msg.Text, msg.HTML)
+
+	client := sendgrid.NewSendClient(n.apiKey)
+
+	log.Printf("Attempting to send email to: %s", msg.ToEmail)
+	response, err := client.SendWithContext(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("email failed with status %d: %s", response.StatusCode, response.Body)
+	}
+
+	log.Printf("Email sent successfully! Status: %d", response.StatusCode)
+	return nil
+}
@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPNotifier sends emails through a plain SMTP relay, using STARTTLS when the server offers it
+type SMTPNotifier struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     Sender
+}
+
+func NewSMTPNotifier(host string, port int, username, password string, from Sender) *SMTPNotifier {
+	return &SMTPNotifier{host: host, port: port, username: username, password: password, from: from}
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if n.from.Email == "" {
+		return fmt.Errorf("SMTP from email not found")
+	}
+
+	body, err := buildMIME(n.from, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	// net/smtp has no context support, so run it in the background and honour cancellation
+	done := make(chan error, 1)
+	go func() {
+		addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+		done <- smtp.SendMail(addr, auth, n.from.Email, []string{msg.ToEmail}, body)
+	}()
+
+	log.Printf("Attempting to send email to: %s", msg.ToEmail)
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+		log.Printf("Email sent successfully via SMTP to %s", msg.ToEmail)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
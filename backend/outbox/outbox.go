@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSending = "sending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+func collection() *mongo.Collection {
+	return initialializers.DB.Collection("outbox")
+}
+
+// NewMessage builds a pending outbox message for one recipient
+func NewMessage(lateSlipID primitive.ObjectID, event, toName, toEmail, subject, text, html string) models.OutboxMessage {
+	now := time.Now()
+	return models.OutboxMessage{
+		ID:            primitive.NewObjectID(),
+		LateSlipID:    lateSlipID,
+		Event:         event,
+		ToName:        toName,
+		ToEmail:       toEmail,
+		Subject:       subject,
+		Text:          text,
+		HTML:          html,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Enqueue stores messages for delivery. Pass the session context of a transaction
+// so the messages are only written together with the change that caused them.
+func Enqueue(ctx context.Context, messages ...models.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	documents := make([]any, 0, len(messages))
+	for _, message := range messages {
+		documents = append(documents, message)
+	}
+	_, err := collection().InsertMany(ctx, documents)
+	return err
+}
+
+// Replay puts a dead (or sent) message back in the queue with a fresh attempt budget.
+// Secret messages have lost their body by then and cannot be replayed.
+func Replay(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	now := time.Now()
+	result, err := collection().UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": []string{StatusDead, StatusSent}}, "secret": bson.M{"$ne": true}},
+		bson.M{
+			"$set":   bson.M{"status": StatusPending, "attempts": 0, "next_attempt_at": now, "updated_at": now},
+			"$unset": bson.M{"last_error": "", "locked_until": "", "sent_at": ""},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
@@ -0,0 +1,177 @@
+package outbox
+
+import (
+	"context"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"lateslip/notifier"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	sendTimeout  = 30 * time.Second
+	lockDuration = 2 * time.Minute
+	baseBackoff  = 30 * time.Second
+	maxBackoff   = time.Hour
+)
+
+// defaultMaxAttempts is how often a message is tried before it is dead-lettered
+const defaultMaxAttempts = 8
+
+// StartWorker delivers queued messages until ctx is cancelled
+func StartWorker(ctx context.Context) {
+	maxAttempts := defaultMaxAttempts
+	if value := os.Getenv("OUTBOX_MAX_ATTEMPTS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			maxAttempts = n
+		} else {
+			log.Printf("Invalid OUTBOX_MAX_ATTEMPTS %q, using %d", value, maxAttempts)
+		}
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// drain everything that is due before waiting again
+		for {
+			message, err := claimNext(ctx)
+			if err != nil {
+				if err != mongo.ErrNoDocuments && ctx.Err() == nil {
+					log.Printf("Outbox worker failed to claim message: %v", err)
+				}
+				break
+			}
+			deliver(ctx, message, maxAttempts)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimNext locks the oldest due message. Messages left in "sending" by a crashed
+// worker are picked up again once their lock expires.
+func claimNext(ctx context.Context) (models.OutboxMessage, error) {
+	now := time.Now()
+	filter := bson.M{"$or": []bson.M{
+		{"status": StatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		{"status": StatusSending, "locked_until": bson.M{"$lte": now}},
+	}}
+	update := bson.M{"$set": bson.M{"status": StatusSending, "locked_until": now.Add(lockDuration), "updated_at": now}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"next_attempt_at": 1}).
+		SetReturnDocument(options.After)
+
+	var message models.OutboxMessage
+	err := collection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&message)
+	return message, err
+}
+
+func deliver(ctx context.Context, message models.OutboxMessage, maxAttempts int) {
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	sendErr := initialializers.Notifier.Send(sendCtx, notifier.Message{
+		ToName:  message.ToName,
+		ToEmail: message.ToEmail,
+		Subject: message.Subject,
+		Text:    message.Text,
+		HTML:    message.HTML,
+	})
+	cancel()
+
+	now := time.Now()
+	attempts := message.Attempts + 1
+	var update bson.M
+	switch {
+	case sendErr == nil:
+		update = bson.M{
+			"$set":   bson.M{"status": StatusSent, "attempts": attempts, "sent_at": now, "updated_at": now},
+			"$unset": finishedFields(message, "locked_until", "last_error"),
+		}
+	case attempts >= maxAttempts:
+		log.Printf("Outbox message %s to %s dead-lettered after %d attempts: %v", message.ID.Hex(), message.ToEmail, attempts, sendErr)
+		update = bson.M{
+			"$set":   bson.M{"status": StatusDead, "attempts": attempts, "last_error": sendErr.Error(), "updated_at": now},
+			"$unset": finishedFields(message, "locked_until"),
+		}
+	default:
+		log.Printf("Outbox message %s to %s failed (attempt %d): %v", message.ID.Hex(), message.ToEmail, attempts, sendErr)
+		update = bson.M{
+			"$set": bson.M{
+				"status":          StatusPending,
+				"attempts":        attempts,
+				"last_error":      sendErr.Error(),
+				"next_attempt_at": now.Add(backoff(attempts)),
+				"updated_at":      now,
+			},
+			"$unset": bson.M{"locked_until": ""},
+		}
+	}
+
+	updateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := collection().UpdateOne(updateCtx, bson.M{"_id": message.ID}, update); err != nil {
+		log.Printf("Failed to update outbox message %s: %v", message.ID.Hex(), err)
+	}
+	recordDelivery(updateCtx, message, sendErr)
+}
+
+// finishedFields lists the fields to remove from a message that will not be tried
+// again; a secret message also loses its body
+func finishedFields(message models.OutboxMessage, fields ...string) bson.M {
+	unset := bson.M{}
+	for _, field := range fields {
+		unset[field] = ""
+	}
+	if message.Secret {
+		unset["text"] = ""
+		unset["html"] = ""
+	}
+	return unset
+}
+
+// backoff doubles the wait after every failed attempt, up to maxBackoff
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// recordDelivery writes the outcome of one attempt to the per-recipient delivery log
+func recordDelivery(ctx context.Context, message models.OutboxMessage, sendErr error) {
+	delivery := models.EmailDelivery{
+		ID:         primitive.NewObjectID(),
+		LateSlipID: message.LateSlipID,
+		OutboxID:   message.ID,
+		Event:      message.Event,
+		Recipient:  message.ToEmail,
+		Subject:    message.Subject,
+		Status:     "sent",
+		CreatedAt:  time.Now(),
+	}
+	if sendErr != nil {
+		delivery.Status = "failed"
+		delivery.Error = sendErr.Error()
+	}
+
+	if _, err := initialializers.DB.Collection("email_deliveries").InsertOne(ctx, delivery); err != nil {
+		log.Printf("Failed to record email delivery to %s: %v", message.ToEmail, err)
+	}
+}
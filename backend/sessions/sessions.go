@@ -0,0 +1,281 @@
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"lateslip/initialializers"
+	"lateslip/models"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// AccessTokenLifetime is short so revoked sessions and changed roles take effect soon
+	AccessTokenLifetime = 15 * time.Minute
+	// RefreshTokenLifetime is how long a session survives without being used
+	RefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+var (
+	ErrNoSecret            = errors.New("JWT secret not configured")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrAccountDisabled     = errors.New("account is disabled")
+)
+
+// Claims are carried by access tokens. SessionID is the family of the refresh token
+// the access token was issued with.
+type Claims struct {
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// Tokens is what a login or refresh hands to the client
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // seconds until AccessToken expires
+}
+
+// Client describes where a session was started or refreshed from
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+func refreshTokens() *mongo.Collection {
+	return initialializers.DB.Collection("refresh_tokens")
+}
+
+func revokedTokens() *mongo.Collection {
+	return initialializers.DB.Collection("revoked_tokens")
+}
+
+func signingKey() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, ErrNoSecret
+	}
+	return []byte(secret), nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseAccessToken checks the signature and expiry of an access token. It does not
+// check the revocation list, see IsRevoked.
+func ParseAccessToken(tokenString string) (*Claims, error) {
+	key, err := signingKey()
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	// tokens from before revocation existed cannot be revoked, make them log in again
+	if claims.ID == "" || claims.UserID == "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// IsRevoked reports whether the access token with this jti was revoked
+func IsRevoked(ctx context.Context, jti string) (bool, error) {
+	err := revokedTokens().FindOne(ctx, bson.M{"_id": jti}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Start logs the user in: it begins a new refresh token family and issues the first
+// pair of tokens
+func Start(ctx context.Context, user models.User, client Client) (Tokens, error) {
+	return issue(ctx, user, primitive.NewObjectID().Hex(), client)
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated means
+// it was stolen or replayed, so the whole family is revoked.
+func Refresh(ctx context.Context, refreshToken string, client Client) (Tokens, error) {
+	now := time.Now()
+	hash := hashToken(refreshToken)
+
+	var current models.RefreshToken
+	err := refreshTokens().FindOneAndUpdate(ctx, bson.M{
+		"token_hash": hash,
+		"rotated_at": bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}, bson.M{"$set": bson.M{"rotated_at": now}}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		var used models.RefreshToken
+		if err := refreshTokens().FindOne(ctx, bson.M{"token_hash": hash}).Decode(&used); err == nil && used.RotatedAt != nil {
+			if err := RevokeFamily(ctx, used.FamilyID); err != nil {
+				return Tokens{}, err
+			}
+		}
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// the role may have changed or the account been disabled since the last refresh
+	userID, err := primitive.ObjectIDFromHex(current.UserID)
+	if err != nil {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	var user models.User
+	if err := initialializers.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Tokens{}, ErrInvalidRefreshToken
+		}
+		return Tokens{}, err
+	}
+	if user.Disabled {
+		if err := RevokeFamily(ctx, current.FamilyID); err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{}, ErrAccountDisabled
+	}
+
+	return issue(ctx, user, current.FamilyID, client)
+}
+
+// issue signs an access token and stores a new refresh token in the family
+func issue(ctx context.Context, user models.User, familyID string, client Client) (Tokens, error) {
+	key, err := signingKey()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	now := time.Now()
+	jti := primitive.NewObjectID().Hex()
+	accessExpiresAt := now.Add(AccessTokenLifetime)
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID:    user.ID.Hex(),
+		Role:      user.Role,
+		SessionID: familyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   user.ID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(accessExpiresAt),
+		},
+	}).SignedString(key)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+	_, err = refreshTokens().InsertOne(ctx, models.RefreshToken{
+		ID:              primitive.NewObjectID(),
+		TokenHash:       hashToken(refreshToken),
+		FamilyID:        familyID,
+		UserID:          user.ID.Hex(),
+		AccessJTI:       jti,
+		AccessExpiresAt: accessExpiresAt,
+		UserAgent:       client.UserAgent,
+		IP:              client.IP,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(RefreshTokenLifetime),
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(AccessTokenLifetime.Seconds()),
+	}, nil
+}
+
+// Logout revokes the family of the refresh token. Unknown tokens are ignored.
+func Logout(ctx context.Context, refreshToken string) error {
+	var token models.RefreshToken
+	err := refreshTokens().FindOne(ctx, bson.M{"token_hash": hashToken(refreshToken)}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return RevokeFamily(ctx, token.FamilyID)
+}
+
+// RevokeFamily ends one session: its refresh tokens stop working and the access
+// tokens issued with them are put on the revocation list
+func RevokeFamily(ctx context.Context, familyID string) error {
+	return revoke(ctx, bson.M{"family_id": familyID})
+}
+
+// RevokeUser ends every session of the user, e.g. when the account is disabled
+func RevokeUser(ctx context.Context, userID string) error {
+	return revoke(ctx, bson.M{"user_id": userID})
+}
+
+func revoke(ctx context.Context, filter bson.M) error {
+	now := time.Now()
+
+	// access tokens that have not expired yet
+	live := bson.M{"access_expires_at": bson.M{"$gt": now}}
+	for key, value := range filter {
+		live[key] = value
+	}
+	cursor, err := refreshTokens().Find(ctx, live)
+	if err != nil {
+		return err
+	}
+	var tokens []models.RefreshToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return err
+	}
+	if len(tokens) > 0 {
+		writes := make([]mongo.WriteModel, 0, len(tokens))
+		for _, token := range tokens {
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": token.AccessJTI}).
+				SetUpdate(bson.M{"$setOnInsert": bson.M{
+					"user_id":    token.UserID,
+					"revoked_at": now,
+					"expires_at": token.AccessExpiresAt,
+				}}).
+				SetUpsert(true))
+		}
+		if _, err := revokedTokens().BulkWrite(ctx, writes); err != nil {
+			return err
+		}
+	}
+
+	filter["revoked_at"] = bson.M{"$exists": false}
+	_, err = refreshTokens().UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now}})
+	return err
+}
@@ -0,0 +1,112 @@
+package slippdf
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Slip is what is printed on an approved late slip. Times are already formatted
+// in campus time.
+type Slip struct {
+	ID          string
+	StudentName string
+	RosterID    string
+	Level       string
+	Semester    string
+	ModuleCode  string
+	ModuleName  string
+	Room        string
+	Instructor  string
+	ClassStart  string
+	Reason      string
+	RequestedAt string
+	ApprovedAt  string
+	ApprovedBy  string
+	ValidUntil  string
+	VerifyURL   string // encoded in the QR code
+}
+
+// maxReason keeps long reasons from pushing the QR code off the page
+const maxReason = 200
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// Render writes a one-page A5 PDF of the slip with a QR code of its verification URL
+func Render(w io.Writer, slip Slip) error {
+	png, err := qrcode.Encode(slip.VerifyURL, qrcode.Medium, 512)
+	if err != nil {
+		return err
+	}
+
+	pdf := fpdf.New("P", "mm", "A5", "")
+	pdf.SetTitle("Late slip "+slip.ID, true)
+	pdf.SetCreator("HeraldSync Late Slip System", true)
+	pdf.SetMargins(12, 12, 12)
+	pdf.SetAutoPageBreak(false, 12)
+	pdf.AddPage()
+	// the core fonts are cp1252; translate so accented names print correctly
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 24
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(contentWidth, 10, "Late Slip", "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(0, 128, 0)
+	pdf.CellFormat(contentWidth, 6, tr("APPROVED - valid until "+slip.ValidUntil), "", 1, "C", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(4)
+
+	module := slip.ModuleCode
+	if slip.ModuleName != "" {
+		module += " " + slip.ModuleName
+	}
+	fields := [][2]string{
+		{"Student", slip.StudentName},
+		{"Roster ID", slip.RosterID},
+		{"Level", slip.Level},
+		{"Semester", slip.Semester},
+		{"Module", module},
+		{"Room", slip.Room},
+		{"Instructor", slip.Instructor},
+		{"Class starts", slip.ClassStart},
+		{"Requested", slip.RequestedAt},
+		{"Approved", slip.ApprovedAt},
+		{"Approved by", slip.ApprovedBy},
+		{"Reason", truncate(slip.Reason, maxReason)},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(30, 6, field[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(contentWidth-30, 6, tr(field[1]), "", "L", false)
+	}
+
+	qrSize := 60.0
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+	pdf.ImageOptions("qr", (pageWidth-qrSize)/2, pdf.GetY()+4, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	pdf.SetY(pdf.GetY() + qrSize + 6)
+
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.MultiCell(contentWidth, 4, "Scan to verify this slip: "+slip.VerifyURL, "", "C", false)
+	pdf.CellFormat(contentWidth, 4, "Slip "+slip.ID, "", 1, "C", false, 0, "")
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
@@ -0,0 +1,93 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// delimiters are tried in order; ties go to the earlier one
+var delimiters = []rune{',', ';', '\t', '|'}
+
+// sniffLines is how many records are used to guess the delimiter
+const sniffLines = 20
+
+func readCSV(data []byte) ([][]string, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := newCSVReader(text, detectDelimiter(text))
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func newCSVReader(text []byte, delimiter rune) *csv.Reader {
+	reader := csv.NewReader(bytes.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader
+}
+
+// decodeText converts the file to UTF-8. Byte order marks identify UTF-8 and UTF-16;
+// text without one that is not valid UTF-8 is taken to be Windows-1252, which is what
+// Excel writes when saving "CSV" on Windows.
+func decodeText(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoder := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+		return decoder.Bytes(data)
+	case utf8.Valid(data):
+		return data, nil
+	default:
+		return charmap.Windows1252.NewDecoder().Bytes(data)
+	}
+}
+
+// detectDelimiter picks the delimiter that splits the first records into the same number
+// of fields, preferring the one that gives the most columns. When none is consistent the
+// header row decides.
+func detectDelimiter(text []byte) rune {
+	best, bestFields := delimiters[0], 0
+	fallback, fallbackFields := delimiters[0], 0
+	for _, delimiter := range delimiters {
+		reader := newCSVReader(text, delimiter)
+		fields, consistent := 0, true
+		for i := 0; i < sniffLines; i++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				consistent = false
+				break
+			}
+			if fields == 0 {
+				fields = len(record)
+			} else if len(record) != fields {
+				consistent = false
+			}
+		}
+		if consistent && fields > 1 && fields > bestFields {
+			best, bestFields = delimiter, fields
+		}
+		if fields > fallbackFields {
+			fallback, fallbackFields = delimiter, fields
+		}
+	}
+	if bestFields == 0 {
+		return fallback
+	}
+	return best
+}
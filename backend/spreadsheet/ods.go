@@ -0,0 +1,194 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	tableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	textNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	officeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+)
+
+// maxRepeat caps the repeat counts OpenDocument uses to describe runs of identical cells,
+// which for trailing blanks often reach the full sheet size
+const maxRepeat = 16384
+
+// maxRows is the most rows read from one sheet, the xlsx limit
+const maxRows = 1 << 20
+
+// readODS reads a sheet from the content.xml of an OpenDocument spreadsheet
+func readODS(data []byte, sheet string) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var content *zip.File
+	for _, file := range archive.File {
+		if file.Name == "content.xml" {
+			content = file
+			break
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("content.xml missing from OpenDocument file")
+	}
+
+	rc, err := content.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != tableNS || start.Name.Local != "table" {
+			continue
+		}
+		if sheet != "" && attr(start, tableNS, "name") != sheet {
+			if err := decoder.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return readODSTable(decoder)
+	}
+	if sheet == "" {
+		return nil, fmt.Errorf("no sheets found")
+	}
+	return nil, fmt.Errorf("sheet %q not found", sheet)
+}
+
+// readODSTable collects the rows of the table whose start element was just read.
+// Trailing blank cells and rows are dropped, like excelize does for xlsx.
+func readODSTable(decoder *xml.Decoder) ([][]string, error) {
+	var rows [][]string
+	var cells []string
+	blankRows, blankCells := 0, 0
+	rowRepeat := 1
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == tableNS && t.Name.Local == "table-row":
+				cells, blankCells = nil, 0
+				rowRepeat = repeat(t, "number-rows-repeated")
+			case t.Name.Space == tableNS && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				text, err := odsCellText(decoder)
+				if err != nil {
+					return nil, err
+				}
+				n := repeat(t, "number-columns-repeated")
+				if text == "" {
+					blankCells += n
+					continue
+				}
+				for ; blankCells > 0 && len(cells) < maxRepeat; blankCells-- {
+					cells = append(cells, "")
+				}
+				for i := 0; i < n && len(cells) < maxRepeat; i++ {
+					cells = append(cells, text)
+				}
+			}
+		case xml.EndElement:
+			switch {
+			case t.Name.Space == tableNS && t.Name.Local == "table-row":
+				if len(cells) == 0 {
+					blankRows += rowRepeat
+					continue
+				}
+				for ; blankRows > 0 && len(rows) < maxRows; blankRows-- {
+					rows = append(rows, nil)
+				}
+				for i := 0; i < rowRepeat && len(rows) < maxRows; i++ {
+					rows = append(rows, append([]string(nil), cells...))
+				}
+			case t.Name.Space == tableNS && t.Name.Local == "table":
+				return rows, nil
+			}
+		}
+	}
+}
+
+// odsCellText returns the displayed text of the cell whose start element was just read.
+// Paragraphs become lines; comments are ignored.
+func odsCellText(decoder *xml.Decoder) (string, error) {
+	var text strings.Builder
+	paragraphs := 0
+	depth := 1
+	for depth > 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case t.Name.Space == officeNS && t.Name.Local == "annotation":
+				if err := decoder.Skip(); err != nil {
+					return "", err
+				}
+				depth--
+			case t.Name.Space == textNS && t.Name.Local == "p":
+				if paragraphs > 0 {
+					text.WriteByte('\n')
+				}
+				paragraphs++
+			case t.Name.Space == textNS && t.Name.Local == "s":
+				text.WriteString(strings.Repeat(" ", min(repeatAttr(t, textNS, "c"), maxRepeat)))
+			case t.Name.Space == textNS && t.Name.Local == "tab":
+				text.WriteByte('\t')
+			case t.Name.Space == textNS && t.Name.Local == "line-break":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if paragraphs > 0 {
+				text.Write(t)
+			}
+		}
+	}
+	return text.String(), nil
+}
+
+func repeat(start xml.StartElement, name string) int {
+	return repeatAttr(start, tableNS, name)
+}
+
+// repeatAttr reads a positive count attribute, defaulting to 1
+func repeatAttr(start xml.StartElement, space, name string) int {
+	n, err := strconv.Atoi(attr(start, space, name))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+func attr(start xml.StartElement, space, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Space == space && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
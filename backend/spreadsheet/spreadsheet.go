@@ -0,0 +1,98 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is the kind of file an upload turned out to be
+type Format string
+
+const (
+	XLSX Format = "xlsx"
+	ODS  Format = "ods"
+	CSV  Format = "csv"
+)
+
+// ErrUnsupported is returned for content that is not a spreadsheet we can read
+var ErrUnsupported = errors.New("unsupported file type")
+
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+// Detect works out the format from the file content, ignoring its name.
+// Zip archives are told apart by their manifest; anything else that looks like text is CSV.
+func Detect(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return "", fmt.Errorf("%w: corrupt zip archive", ErrUnsupported)
+		}
+		for _, file := range archive.File {
+			switch file.Name {
+			case "[Content_Types].xml", "xl/workbook.xml":
+				return XLSX, nil
+			case "mimetype":
+				mimetype, err := readZipFile(file)
+				if err == nil && strings.HasPrefix(strings.TrimSpace(string(mimetype)), odsMimeType) {
+					return ODS, nil
+				}
+			}
+		}
+		return "", fmt.Errorf("%w: zip archive is not a spreadsheet", ErrUnsupported)
+	case bytes.HasPrefix(data, []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")):
+		return "", fmt.Errorf("%w: legacy .xls workbooks are not supported, save as .xlsx or CSV", ErrUnsupported)
+	case looksLikeText(data):
+		return CSV, nil
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+// Read returns the rows of the uploaded file. For workbooks the named sheet is read, or the
+// active (first, for .ods) sheet when sheet is empty; CSV files have a single sheet.
+func Read(data []byte, sheet string) ([][]string, Format, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var rows [][]string
+	switch format {
+	case XLSX:
+		rows, err = readXLSX(data, sheet)
+	case ODS:
+		rows, err = readODS(data, sheet)
+	case CSV:
+		rows, err = readCSV(data)
+	}
+	return rows, format, err
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// looksLikeText rejects content with NUL bytes, unless it is UTF-16 with a byte order mark
+func looksLikeText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return true
+	}
+	sample := data
+	if len(sample) > 4096 {
+		sample = sample[:4096]
+	}
+	return bytes.IndexByte(sample, 0) < 0
+}
@@ -0,0 +1,219 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zipOf builds an archive holding the given files, in order
+func zipOf(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, file := range files {
+		f, err := w.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const odsContent = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content
+    xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+    xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+    xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+  <office:body><office:spreadsheet>
+    <table:table table:name="Other">
+      <table:table-row><table:table-cell><text:p>skipped</text:p></table:table-cell></table:table-row>
+    </table:table>
+    <table:table table:name="Students">
+      <table:table-row>
+        <table:table-cell><text:p>a</text:p></table:table-cell>
+        <table:table-cell table:number-columns-repeated="2"/>
+        <table:table-cell table:number-columns-repeated="2"><text:p>b</text:p></table:table-cell>
+        <table:table-cell table:number-columns-repeated="16000"/>
+      </table:table-row>
+      <table:table-row table:number-rows-repeated="2">
+        <table:table-cell table:number-columns-repeated="16384"/>
+      </table:table-row>
+      <table:table-row table:number-rows-repeated="2">
+        <table:table-cell><text:p>x<text:s text:c="2"/>y</text:p><text:p>z</text:p></table:table-cell>
+        <table:table-cell><office:annotation><text:p>note</text:p></office:annotation><text:p>c</text:p></table:table-cell>
+      </table:table-row>
+      <table:table-row table:number-rows-repeated="1048000">
+        <table:table-cell table:number-columns-repeated="16384"/>
+      </table:table-row>
+    </table:table>
+  </office:spreadsheet></office:body>
+</office:document-content>`
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Format
+		wantErr bool
+	}{
+		{"xlsx", zipOf(t, [2]string{"[Content_Types].xml", "<Types/>"}), XLSX, false},
+		{"xlsx without content types", zipOf(t, [2]string{"xl/workbook.xml", "<workbook/>"}), XLSX, false},
+		{"ods", zipOf(t, [2]string{"mimetype", odsMimeType}, [2]string{"content.xml", odsContent}), ODS, false},
+		{"odt", zipOf(t, [2]string{"mimetype", "application/vnd.oasis.opendocument.text"}), "", true},
+		{"plain zip", zipOf(t, [2]string{"notes.txt", "hello"}), "", true},
+		{"corrupt zip", []byte("PK\x03\x04garbage"), "", true},
+		{"legacy xls", []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1rest"), "", true},
+		{"csv", []byte("a,b\n1,2\n"), CSV, false},
+		{"utf-16 csv", []byte{0xFF, 0xFE, 'a', 0, ',', 0, 'b', 0}, CSV, false},
+		{"binary", []byte{0x89, 'P', 'N', 'G', 0, 0}, "", true},
+		{"empty", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(tt.data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("Detect() error = %v, want ErrUnsupported", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Detect() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLegacyXLSMessage(t *testing.T) {
+	_, err := Detect([]byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"))
+	if err == nil || !strings.Contains(err.Error(), ".xlsx") {
+		t.Fatalf("Detect() error = %v, want a hint to save as .xlsx", err)
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want rune
+	}{
+		{"comma", "a,b,c\n1,2,3\n", ','},
+		{"semicolon", "a;b;c\n1;2;3\n", ';'},
+		{"tab", "a\tb\tc\n1\t2\t3\n", '\t'},
+		{"pipe", "a|b\n1|2\n", '|'},
+		{"semicolon with decimal commas", "name;amount\nAda;1,5\nBo;2,25\n", ';'},
+		{"most columns wins", "a,b;c;d\n1,2;3;4\n", ';'},
+		{"inconsistent falls back to header", "a;b;c\n1;2\n", ';'},
+		{"single column", "name\nAda\n", ','},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectDelimiter([]byte(tt.text)); got != tt.want {
+				t.Fatalf("detectDelimiter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"utf-8", []byte("Zoë,Müller"), "Zoë,Müller"},
+		{"utf-8 with bom", []byte("\xEF\xBB\xBFZoë"), "Zoë"},
+		{"utf-16 le", []byte{0xFF, 0xFE, 'Z', 0, 'o', 0, 0xEB, 0}, "Zoë"},
+		{"utf-16 be", []byte{0xFE, 0xFF, 0, 'Z', 0, 'o', 0, 0xEB}, "Zoë"},
+		{"windows-1252", []byte("Zo\xEB \x80"), "Zoë €"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeText(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("decodeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	rows, format, err := Read([]byte("\xEF\xBB\xBFid;name\n1;\"Zoë; jr\"\n2\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"id", "name"}, {"1", "Zoë; jr"}, {"2"}}
+	if format != CSV || !reflect.DeepEqual(rows, want) {
+		t.Fatalf("Read() = %q, %q, want %q", rows, format, want)
+	}
+}
+
+func TestReadODS(t *testing.T) {
+	data := zipOf(t, [2]string{"mimetype", odsMimeType}, [2]string{"content.xml", odsContent})
+
+	rows, format, err := Read(data, "Students")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"a", "", "", "b", "b"},
+		nil,
+		nil,
+		{"x  y\nz", "c"},
+		{"x  y\nz", "c"},
+	}
+	if format != ODS || !reflect.DeepEqual(rows, want) {
+		t.Fatalf("Read() = %q, %q, want %q", rows, format, want)
+	}
+
+	rows, _, err = Read(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"skipped"}}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("Read() first sheet = %q, want %q", rows, want)
+	}
+
+	if _, _, err := Read(data, "Missing"); err == nil {
+		t.Fatal("Read() of a missing sheet succeeded")
+	}
+}
+
+func TestReadODSRepeatCaps(t *testing.T) {
+	tests := []struct {
+		name          string
+		row           string
+		rows, columns int
+	}{
+		{"columns", `<table:table-row><table:table-cell table:number-columns-repeated="100000"><text:p>v</text:p></table:table-cell></table:table-row>`, 1, maxRepeat},
+		{"rows", `<table:table-row table:number-rows-repeated="2000000"><table:table-cell><text:p>v</text:p></table:table-cell></table:table-row>`, maxRows, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `<table:table xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">` +
+				tt.row + `</table:table>`
+			data := zipOf(t, [2]string{"mimetype", odsMimeType}, [2]string{"content.xml", content})
+
+			rows, _, err := Read(data, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != tt.rows || len(rows[0]) != tt.columns {
+				t.Fatalf("Read() = %d rows of %d cells, want %d of %d", len(rows), len(rows[0]), tt.rows, tt.columns)
+			}
+		})
+	}
+}
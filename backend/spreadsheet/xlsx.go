@@ -0,0 +1,24 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func readXLSX(data []byte, sheet string) ([][]string, error) {
+	xlsx, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer xlsx.Close()
+
+	if sheet == "" {
+		sheet = xlsx.GetSheetName(xlsx.GetActiveSheetIndex())
+	}
+	if index, err := xlsx.GetSheetIndex(sheet); err != nil || index < 0 {
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+	return xlsx.GetRows(sheet)
+}